@@ -6,6 +6,7 @@ require (
 	github.com/hashicorp/hcl/v2 v2.19.1
 	github.com/hashicorp/packer-plugin-sdk v0.5.2
 	github.com/zclconf/go-cty v1.13.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,262 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+// Package communicatortest offers an in-memory packersdk.Communicator for unit-testing the Provision of provisioners
+// end-to-end in plain "go test" runs, without a remote machine, Docker or a cloud account.
+//
+// The Communicator records every upload, directory upload and command. Commands exit with 0 unless told otherwise by
+// On, except for the existence checks of shell.Step guards, which succeed only for uploaded paths whether or not they run
+// with sudo:
+//
+//	communicator := communicatortest.New().On("docker volume inspect nexus-data", 1, "")
+//	err := provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil)
+//	scripts := communicator.Scripts()
+package communicatortest
+
+import (
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// UbuntuOsRelease The /etc/os-release of Ubuntu 22.04, which a Communicator reports by default
+const UbuntuOsRelease string = `PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+ID=ubuntu
+ID_LIKE=debian
+`
+
+// DefaultMachine The hardware name of the fake machine that a Communicator reports by default, as printed by "uname -m"
+const DefaultMachine string = "x86_64"
+
+// Upload A file uploaded to Path in the fake machine
+type Upload struct {
+	Path    string
+	Content []byte
+	Mode    os.FileMode
+}
+
+// DirUpload A local directory uploaded to Destination in the fake machine
+type DirUpload struct {
+	Destination string
+	Source      string
+	Exclude     []string
+}
+
+type response struct {
+	match      string
+	exitStatus int
+	stdout     string
+}
+
+// Communicator An in-memory fake of a remote machine. Use New to create one
+type Communicator struct {
+	// OsRelease The content of /etc/os-release of the fake machine
+	OsRelease string
+	// Machine The hardware name of the fake machine, as printed by "uname -m", such as "aarch64"
+	Machine string
+
+	// Uploads The uploaded files, in order
+	Uploads []Upload
+	// DirUploads The uploaded directories, in order
+	DirUploads []DirUpload
+	// Commands The commands started in the fake machine, in order
+	Commands []string
+
+	responses []response
+	mutex     sync.Mutex
+}
+
+// New Returns a Communicator of a fake x86_64 Ubuntu machine
+func New() *Communicator {
+	return &Communicator{OsRelease: UbuntuOsRelease, Machine: DefaultMachine}
+}
+
+// On Makes every command containing match exit with exitStatus and print stdout. For a command that runs a script
+// uploaded by shell.Provision, the content of the script is matched as well, so that a step fails by matching one of
+// its commands. Rules added later take precedence.
+//
+// Returns:
+// The Communicator itself, so that rules can be chained
+func (c *Communicator) On(match string, exitStatus int, stdout string) *Communicator {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.responses = append(c.responses, response{match, exitStatus, stdout})
+	return c
+}
+
+// Scripts Returns the content of every script that has been run, in order, which for shell.Provision is one script
+// per step that is not skipped
+func (c *Communicator) Scripts() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var scripts []string
+	for _, command := range c.Commands {
+		if script, ok := c.script(command); ok {
+			scripts = append(scripts, script)
+		}
+	}
+
+	return scripts
+}
+
+// Upload Records a file uploaded to the path together with its content and permissions
+func (c *Communicator) Upload(path string, input io.Reader, fi *os.FileInfo) error {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if fi != nil {
+		mode = (*fi).Mode().Perm()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Uploads = append(c.Uploads, Upload{Path: path, Content: content, Mode: mode})
+	return nil
+}
+
+// Uploaded Returns the last file uploaded to the path, or nil if none was
+func (c *Communicator) Uploaded(path string) *Upload {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.uploaded(path)
+}
+
+func (c *Communicator) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.mutex.Lock()
+	c.Commands = append(c.Commands, cmd.Command)
+	exitStatus, stdout := c.respond(cmd.Command)
+	c.mutex.Unlock()
+
+	// Output is written asynchronously, as in a real machine, since RemoteCmd.RunWithUi reads it only after Start
+	// returns
+	go func() {
+		if stdout != "" && cmd.Stdout != nil {
+			io.WriteString(cmd.Stdout, stdout)
+		}
+		cmd.SetExited(exitStatus)
+	}()
+
+	return nil
+}
+
+func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.DirUploads = append(c.DirUploads, DirUpload{Destination: dst, Source: src, Exclude: exclude})
+	return nil
+}
+
+// Download Writes the content of the last file uploaded to the path
+func (c *Communicator) Download(path string, output io.Writer) error {
+	c.mutex.Lock()
+	upload := c.uploaded(path)
+	c.mutex.Unlock()
+
+	if upload == nil {
+		return fmt.Errorf("no such file in fake machine: '%s'", path)
+	}
+
+	_, err := output.Write(upload.Content)
+	return err
+}
+
+func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
+	return fmt.Errorf("downloading directories from fake machine is not supported: '%s'", src)
+}
+
+func (c *Communicator) respond(command string) (int, string) {
+	script, _ := c.script(command)
+	for i := len(c.responses) - 1; i >= 0; i-- {
+		r := c.responses[i]
+		if strings.Contains(command, r.match) || (script != "" && strings.Contains(script, r.match)) {
+			return r.exitStatus, r.stdout
+		}
+	}
+
+	if command == distro.OsReleaseCommand {
+		return 0, c.OsRelease
+	}
+	if command == distro.ArchCommand {
+		return 0, c.Machine + "\n"
+	}
+
+	if path, ok := strings.CutPrefix(strings.TrimPrefix(command, "sudo "), "test -e "); ok {
+		if c.exists(strings.Trim(path, "'")) {
+			return 0, ""
+		}
+		return 1, ""
+	}
+
+	return 0, ""
+}
+
+// Returns the content of the uploaded script that a command runs the way shell.Provision does, i.e.
+// "chmod +x <script>; <script>"
+func (c *Communicator) script(command string) (string, bool) {
+	if !strings.HasPrefix(command, "chmod +x ") {
+		return "", false
+	}
+
+	_, path, found := strings.Cut(command, "; ")
+	if !found {
+		return "", false
+	}
+
+	upload := c.uploaded(path)
+	if upload == nil {
+		return "", false
+	}
+
+	return string(upload.Content), true
+}
+
+func (c *Communicator) uploaded(path string) *Upload {
+	for i := len(c.Uploads) - 1; i >= 0; i-- {
+		if c.Uploads[i].Path == path {
+			return &c.Uploads[i]
+		}
+	}
+
+	return nil
+}
+
+// Returns whether a path, which is relative to the home directory if not absolute, has been uploaded, either as a file
+// or as a directory containing an uploaded file
+func (c *Communicator) exists(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	matches := func(destination string) bool {
+		destination = "/" + strings.TrimPrefix(strings.TrimSuffix(destination, "/"), "/")
+		if strings.HasPrefix(path, "/") {
+			return destination == path || strings.HasPrefix(destination, path+"/")
+		}
+		return strings.HasSuffix(destination, "/"+path) || strings.Contains(destination, "/"+path+"/")
+	}
+
+	for _, upload := range c.Uploads {
+		if matches(upload.Path) {
+			return true
+		}
+	}
+	for _, upload := range c.DirUploads {
+		if matches(upload.Destination) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package communicatortest
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"strings"
+	"testing"
+)
+
+func TestCommunicator(t *testing.T) {
+	communicator := New().On("false", 3, "")
+	communicator.Upload("/home/ubuntu/docker-kong/README.md", strings.NewReader("docker-kong"), nil)
+
+	steps := []shell.Step{
+		{Name: "Installing Nginx", Commands: []string{"sudo apt install -y nginx"}},
+		{Name: "Cloning docker-kong", Commands: []string{"git clone https://github.com/QubitPi/docker-kong.git"}, Creates: "docker-kong"},
+		{Name: "Failing", Commands: []string{"false"}},
+	}
+	err := shell.Provision(context.Background(), packersdk.TestUi(t), communicator, steps)
+
+	if err == nil || !strings.Contains(err.Error(), "Step 3/3: Failing") {
+		t.Errorf("Expected the last step to fail, got: %v", err)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 2 {
+		t.Fatalf("Expected 2 scripts to run, since the second step is satisfied already, got: %s", scripts)
+	}
+	if !strings.Contains(scripts[0], "sudo apt install -y nginx\n") {
+		t.Errorf("Expected the first script to install Nginx, got:\n%s", scripts[0])
+	}
+}
+
+func TestCommunicatorOsRelease(t *testing.T) {
+	communicator := New()
+	communicator.OsRelease = "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n"
+
+	d, err := distro.Detect(context.Background(), communicator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.ID != "rocky" {
+		t.Errorf("Expected 'rocky', got '%s'", d.ID)
+	}
+}
+
+func TestCommunicatorMachine(t *testing.T) {
+	communicator := New()
+	communicator.Machine = "aarch64"
+
+	arch, err := distro.DetectArch(context.Background(), communicator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if arch != distro.Arm64 {
+		t.Errorf("Expected '%s', got '%s'", distro.Arm64, arch)
+	}
+}
+
+func TestCommunicatorDownload(t *testing.T) {
+	communicator := New()
+	communicator.Upload("/home/ubuntu/compose.yaml", strings.NewReader("services:"), nil)
+
+	var downloaded strings.Builder
+	if err := communicator.Download("/home/ubuntu/compose.yaml", &downloaded); err != nil {
+		t.Fatal(err)
+	}
+	if downloaded.String() != "services:" {
+		t.Errorf("Expected 'services:', got '%s'", downloaded.String())
+	}
+
+	if err := communicator.Download("/home/ubuntu/mailserver.env", &downloaded); err == nil {
+		t.Error("Expected error downloading a file that has not been uploaded")
+	}
+}
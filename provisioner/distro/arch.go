@@ -0,0 +1,51 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package distro
+
+import (
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"strings"
+)
+
+// Arch The hardware architecture of a machine, named the way Debian names it in package and directory names
+type Arch string
+
+const (
+	Amd64 Arch = "amd64"
+	Arm64 Arch = "arm64"
+)
+
+// ArchCommand The command that DetectArch runs in remote machine to read its hardware architecture
+const ArchCommand string = "uname -m"
+
+// DetectArch Reads the hardware architecture of remote machine, such as an x86_64 EC2 instance or an aarch64 Graviton
+// one.
+//
+// Returns:
+// The architecture, or an error if it cannot be read or is not supported
+func DetectArch(ctx context.Context, communicator packersdk.Communicator) (Arch, error) {
+	machine, err := run(ctx, communicator, ArchCommand)
+	if err != nil {
+		return "", fmt.Errorf("error reading hardware architecture of remote machine: %s", err)
+	}
+
+	return ParseArch(machine)
+}
+
+// ParseArch Returns the architecture of a machine hardware name as printed by "uname -m", such as "x86_64".
+//
+// Returns:
+// The architecture, or an error if the architecture is not supported
+func ParseArch(machine string) (Arch, error) {
+	switch strings.TrimSpace(machine) {
+	case "x86_64", "amd64":
+		return Amd64, nil
+	case "aarch64", "arm64":
+		return Arm64, nil
+	default:
+		return "", fmt.Errorf("unsupported hardware architecture '%s'; supported are x86_64 and aarch64", strings.TrimSpace(machine))
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package distro
+
+import (
+	"testing"
+)
+
+func TestParseArch(t *testing.T) {
+	data := []struct {
+		name      string
+		machine   string
+		expected  Arch
+		expectErr bool
+	}{
+		{"x86_64", "x86_64\n", Amd64, false},
+		{"aarch64", "aarch64\n", Arm64, false},
+		{"arm64", "arm64", Arm64, false},
+		{"s390x", "s390x\n", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			actual, err := ParseArch(d.machine)
+			if (err != nil) != d.expectErr {
+				t.Fatalf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+			if actual != d.expected {
+				t.Errorf("Expected '%s', got '%s'", d.expected, actual)
+			}
+		})
+	}
+}
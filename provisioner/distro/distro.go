@@ -0,0 +1,189 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+// Package distro detects the Linux distribution of the remote machine and abstracts over its package manager, so that
+// provisioners are not tied to apt-based Ubuntu
+package distro
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"io"
+	"strings"
+)
+
+// PackageManager The system package manager of a Linux distribution
+type PackageManager string
+
+const (
+	Apt PackageManager = "apt"
+	Dnf PackageManager = "dnf"
+	Yum PackageManager = "yum"
+	Apk PackageManager = "apk"
+)
+
+// OsReleaseCommand The command that Detect runs in remote machine to read its /etc/os-release
+const OsReleaseCommand string = "cat /etc/os-release"
+
+// Distro The Linux distribution of remote machine, as described by its /etc/os-release
+type Distro struct {
+	// ID The lower-case distribution ID, such as "ubuntu", "amzn" or "rocky"
+	ID string
+	// VersionID The distribution version, such as "22.04" or "2023"
+	VersionID string
+	// IDLike The IDs of distributions this one is derived from, such as "rhel centos fedora" for Rocky Linux
+	IDLike []string
+	// PackageManager The package manager used to install packages
+	PackageManager PackageManager
+}
+
+// Detect Reads /etc/os-release of remote machine and returns its distribution.
+//
+// Returns:
+// The distribution, or an error if it cannot be read or is not supported
+func Detect(ctx context.Context, communicator packersdk.Communicator) (*Distro, error) {
+	osRelease, err := run(ctx, communicator, OsReleaseCommand)
+	if err != nil {
+		return nil, fmt.Errorf("error reading /etc/os-release in remote machine: %s", err)
+	}
+
+	return Parse(osRelease)
+}
+
+// Parse Returns the distribution described by the content of an /etc/os-release file.
+//
+// Returns:
+// The distribution, or an error if the distribution is not supported
+func Parse(osRelease string) (*Distro, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(osRelease))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		fields[key] = strings.Trim(value, `"'`)
+	}
+
+	d := &Distro{
+		ID:        strings.ToLower(fields["ID"]),
+		VersionID: fields["VERSION_ID"],
+		IDLike:    strings.Fields(strings.ToLower(fields["ID_LIKE"])),
+	}
+
+	packageManager, err := d.detectPackageManager()
+	if err != nil {
+		return nil, err
+	}
+	d.PackageManager = packageManager
+
+	return d, nil
+}
+
+// Is Returns whether the distribution is, or is derived from, any of the provided distribution IDs
+func (d *Distro) Is(ids ...string) bool {
+	for _, id := range ids {
+		if d.ID == id {
+			return true
+		}
+		for _, like := range d.IDLike {
+			if like == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// UpgradeCommand Returns the command that refreshes the package index and upgrades all installed packages
+func (d *Distro) UpgradeCommand() string {
+	switch d.PackageManager {
+	case Apt:
+		return "sudo apt update && sudo apt upgrade -y"
+	case Apk:
+		return "sudo apk update && sudo apk upgrade"
+	default:
+		return fmt.Sprintf("sudo %s update -y", d.PackageManager)
+	}
+}
+
+// InstallCommand Returns the command that installs the provided packages without prompting
+func (d *Distro) InstallCommand(packages ...string) string {
+	if d.PackageManager == Apk {
+		return fmt.Sprintf("sudo apk add %s", strings.Join(packages, " "))
+	}
+
+	return fmt.Sprintf("sudo %s install -y %s", d.PackageManager, strings.Join(packages, " "))
+}
+
+// InstallLocalCommand Returns the command that installs all package files of the distribution's format, such as
+// ".deb" for apt, in a directory of remote machine. It needs no network access as long as the dependencies of those
+// packages are either installed already or among the package files
+func (d *Distro) InstallLocalCommand(dir string) string {
+	switch d.PackageManager {
+	case Apt:
+		return fmt.Sprintf("sudo apt install -y %s/*.deb", dir)
+	case Apk:
+		return fmt.Sprintf("sudo apk add --allow-untrusted %s/*.apk", dir)
+	default:
+		return fmt.Sprintf("sudo %s install -y %s/*.rpm", d.PackageManager, dir)
+	}
+}
+
+// Systemd Returns whether the distribution runs systemd as its init system. Alpine runs OpenRC instead
+func (d *Distro) Systemd() bool {
+	return d.PackageManager != Apk
+}
+
+// ServiceEnableCommand Returns the command that starts a system service and enables it at boot
+func (d *Distro) ServiceEnableCommand(service string) string {
+	if !d.Systemd() {
+		return fmt.Sprintf("sudo rc-update add %s default && sudo rc-service %s start", service, service)
+	}
+
+	return fmt.Sprintf("sudo systemctl enable --now %s", service)
+}
+
+func (d *Distro) detectPackageManager() (PackageManager, error) {
+	switch {
+	case d.Is("debian", "ubuntu"):
+		return Apt, nil
+	case d.ID == "amzn":
+		if d.VersionID == "2" {
+			return Yum, nil
+		}
+		return Dnf, nil
+	case d.Is("rhel", "centos", "fedora"):
+		if strings.HasPrefix(d.VersionID, "7") && d.ID != "fedora" {
+			return Yum, nil
+		}
+		return Dnf, nil
+	case d.Is("alpine"):
+		return Apk, nil
+	default:
+		return "", fmt.Errorf(
+			"unsupported Linux distribution '%s' (ID_LIKE: '%s'); supported are Debian, Ubuntu, Amazon Linux, "+
+				"RHEL-compatible distributions such as Rocky Linux, Fedora and Alpine",
+			d.ID,
+			strings.Join(d.IDLike, " "),
+		)
+	}
+}
+
+// Runs a command in remote machine and returns its standard output
+func run(ctx context.Context, communicator packersdk.Communicator, command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &stdout, Stderr: io.Discard}
+	if err := communicator.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("exit status %d", status)
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package distro
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []struct {
+		name                   string
+		osRelease              string
+		expectedID             string
+		expectedPackageManager PackageManager
+		expectErr              bool
+	}{
+		{
+			"Ubuntu",
+			"NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nID=ubuntu\nID_LIKE=debian\n",
+			"ubuntu",
+			Apt,
+			false,
+		},
+		{"Debian", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nVERSION_ID=\"12\"\nID=debian\n", "debian", Apt, false},
+		{
+			"Amazon Linux 2023",
+			"NAME=\"Amazon Linux\"\nVERSION_ID=\"2023\"\nID=\"amzn\"\nID_LIKE=\"fedora\"\n",
+			"amzn",
+			Dnf,
+			false,
+		},
+		{
+			"Amazon Linux 2",
+			"NAME=\"Amazon Linux\"\nVERSION_ID=\"2\"\nID=\"amzn\"\nID_LIKE=\"centos rhel fedora\"\n",
+			"amzn",
+			Yum,
+			false,
+		},
+		{
+			"Rocky Linux 9",
+			"NAME=\"Rocky Linux\"\nVERSION_ID=\"9.3\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n",
+			"rocky",
+			Dnf,
+			false,
+		},
+		{"CentOS 7", "NAME=\"CentOS Linux\"\nVERSION_ID=\"7\"\nID=\"centos\"\nID_LIKE=\"rhel fedora\"\n", "centos", Yum, false},
+		{"Fedora", "NAME=\"Fedora Linux\"\nVERSION_ID=39\nID=fedora\n", "fedora", Dnf, false},
+		{"Alpine", "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.19.0\n", "alpine", Apk, false},
+		{"comments and blank lines", "# generated\n\nID=ubuntu\n", "ubuntu", Apt, false},
+		{"Arch Linux", "NAME=\"Arch Linux\"\nID=arch\n", "", "", true},
+		{"openSUSE", "NAME=\"openSUSE Leap\"\nID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\n", "", "", true},
+		{"empty", "", "", "", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			actual, err := Parse(d.osRelease)
+			if (err != nil) != d.expectErr {
+				t.Fatalf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if actual.ID != d.expectedID {
+				t.Errorf("Expected ID '%s', got '%s'", d.expectedID, actual.ID)
+			}
+			if actual.PackageManager != d.expectedPackageManager {
+				t.Errorf("Expected package manager '%s', got '%s'", d.expectedPackageManager, actual.PackageManager)
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	data := []struct {
+		name            string
+		packageManager  PackageManager
+		expectedUpgrade string
+		expectedInstall string
+		expectedLocal   string
+		expectedEnable  string
+	}{
+		{
+			"apt",
+			Apt,
+			"sudo apt update && sudo apt upgrade -y",
+			"sudo apt install -y nginx curl",
+			"sudo apt install -y /home/ubuntu/packages/*.deb",
+			"sudo systemctl enable --now nginx",
+		},
+		{
+			"dnf",
+			Dnf,
+			"sudo dnf update -y",
+			"sudo dnf install -y nginx curl",
+			"sudo dnf install -y /home/ubuntu/packages/*.rpm",
+			"sudo systemctl enable --now nginx",
+		},
+		{
+			"yum",
+			Yum,
+			"sudo yum update -y",
+			"sudo yum install -y nginx curl",
+			"sudo yum install -y /home/ubuntu/packages/*.rpm",
+			"sudo systemctl enable --now nginx",
+		},
+		{
+			"apk",
+			Apk,
+			"sudo apk update && sudo apk upgrade",
+			"sudo apk add nginx curl",
+			"sudo apk add --allow-untrusted /home/ubuntu/packages/*.apk",
+			"sudo rc-update add nginx default && sudo rc-service nginx start",
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			distro := &Distro{PackageManager: d.packageManager}
+
+			if actual := distro.UpgradeCommand(); actual != d.expectedUpgrade {
+				t.Errorf("Expected '%s', got '%s'", d.expectedUpgrade, actual)
+			}
+			if actual := distro.InstallCommand("nginx", "curl"); actual != d.expectedInstall {
+				t.Errorf("Expected '%s', got '%s'", d.expectedInstall, actual)
+			}
+			if actual := distro.InstallLocalCommand("/home/ubuntu/packages"); actual != d.expectedLocal {
+				t.Errorf("Expected '%s', got '%s'", d.expectedLocal, actual)
+			}
+			if actual := distro.ServiceEnableCommand("nginx"); actual != d.expectedEnable {
+				t.Errorf("Expected '%s', got '%s'", d.expectedEnable, actual)
+			}
+		})
+	}
+}
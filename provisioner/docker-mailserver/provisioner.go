@@ -12,18 +12,26 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/render"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
 	"path/filepath"
 	"strings"
 )
 
 type Config struct {
-	SslCertBase64    string `mapstructure:"sslCertBase64" required:"true"`
-	SslCertKeyBase64 string `mapstructure:"sslCertKeyBase64" required:"true"`
-	BaseDomain       string `mapstructure:"baseDomain" required:"true"`
-	HomeDir          string `mapstructure:"homeDir" required:"false"`
+	BaseDomain string `mapstructure:"baseDomain" required:"true"`
+	HomeDir    string `mapstructure:"homeDir" required:"false"`
+
+	Offline              bool   `mapstructure:"offline" required:"false"`
+	DockerPackagesSource string `mapstructure:"dockerPackagesSource" required:"false"`
+	MailserverEnvSource  string `mapstructure:"mailserverEnvSource" required:"false"`
+
+	Ssl    ssl.Config    `mapstructure:",squash"`
+	DryRun render.Config `mapstructure:",squash"`
 
 	ctx interpolate.Context
 }
@@ -42,39 +50,99 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		return err
 	}
 
-	return nil
+	errs := validation.CheckRequired(&p.config)
+	errs = append(errs, validation.CheckDomain("baseDomain", p.config.BaseDomain))
+	errs = append(errs, p.config.Ssl.Validate("mail."+p.config.BaseDomain)...)
+	errs = append(
+		errs,
+		validation.CheckOfflineSource(p.config.Offline, "dockerPackagesSource", p.config.DockerPackagesSource),
+		validation.CheckOfflineSource(p.config.Offline, "mailserverEnvSource", p.config.MailserverEnvSource),
+		p.config.Ssl.CheckOffline(p.config.Offline),
+	)
+	errs = append(errs, p.config.DryRun.Validate()...)
+
+	return validation.Combine(errs...)
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, generatedData map[string]interface{}) error {
+	return p.config.DryRun.Run(ctx, ui, communicator, p.provision)
+}
+
+func (p *Provisioner) provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
 	p.config.HomeDir = ssl.GetHomeDir(p.config.HomeDir)
 
+	d, err := distro.Detect(ctx, communicator)
+	if err != nil {
+		return err
+	}
+
 	mailServerDomain := "mail." + p.config.BaseDomain
 
 	composeFile := strings.Replace(getDockerComposeFileTemplate(), "mail.domain.com", mailServerDomain, -1)
 	composeFileDst := fmt.Sprintf(filepath.Join(p.config.HomeDir, "compose.yaml"))
-	composeFileSource, err := ssl.WriteToFile(composeFile)
-	err = file.Provision(p.config.ctx, ui, communicator, composeFileSource, composeFileDst)
+	if err := ssl.UploadContent(p.config.ctx, ui, communicator, composeFile, composeFileDst); err != nil {
+		return err
+	}
+
+	dockerStep, err := shell.StepInstallingDocker(p.config.ctx, ui, communicator, d, p.config.HomeDir, p.config.DockerPackagesSource)
 	if err != nil {
-		return fmt.Errorf("error uploading '%s' to '%s': %s", composeFileSource, composeFileDst, err)
+		return err
 	}
 
-	sslCert, err := ssl.DecodeBase64(p.config.SslCertBase64)
-	sslCertSource, err := ssl.WriteToFile(sslCert)
-	sslCertDestination := fmt.Sprintf(filepath.Join(p.config.HomeDir, "fullchain.pem"))
-	err = file.Provision(p.config.ctx, ui, communicator, sslCertSource, sslCertDestination)
+	if p.config.MailserverEnvSource != "" {
+		mailserverEnvDst := filepath.Join(p.config.HomeDir, "mailserver.env")
+		if err = file.Provision(p.config.ctx, ui, communicator, p.config.MailserverEnvSource, mailserverEnvDst); err != nil {
+			return err
+		}
+	}
+	steps := getSteps(d, dockerStep, p.config.MailserverEnvSource != "")
+
+	if p.config.Ssl.IsAcme() {
+		if err := shell.Provision(ctx, ui, communicator, steps); err != nil {
+			return err
+		}
+
+		return ssl.ProvisionAcmeCertificate(
+			ctx,
+			p.config.ctx,
+			ui,
+			communicator,
+			d,
+			p.config.HomeDir,
+			p.config.Ssl,
+			mailServerDomain,
+			"",
+			getCertbotConfigDir(p.config.HomeDir),
+			"",
+		)
+	}
+
+	keyPair, err := p.config.Ssl.LoadKeyPair(mailServerDomain)
 	if err != nil {
-		return fmt.Errorf("error uploading '%s' to '%s': %s", sslCertSource, sslCertDestination, err)
+		return err
 	}
+	p.config.Ssl.WarnIfExpiringSoon(ui, keyPair)
 
-	sslCertKey, err := ssl.DecodeBase64(p.config.SslCertKeyBase64)
-	sslCertKeySource, err := ssl.WriteToFile(sslCertKey)
+	sslCertDestination := fmt.Sprintf(filepath.Join(p.config.HomeDir, "fullchain.pem"))
 	sslCertKeyDestination := fmt.Sprintf(filepath.Join(p.config.HomeDir, "privkey.pem"))
-	err = file.Provision(p.config.ctx, ui, communicator, sslCertKeySource, sslCertKeyDestination)
+	err = p.config.Ssl.UploadKeyPair(p.config.ctx, ui, communicator, keyPair, sslCertDestination, sslCertKeyDestination)
 	if err != nil {
-		return fmt.Errorf("error uploading '%s' to '%s': %s", sslCertKeySource, sslCertKeyDestination, err)
+		return err
 	}
 
-	return shell.Provision(ctx, ui, communicator, getCommands(p.config.HomeDir, mailServerDomain, sslCertDestination, sslCertKeyDestination))
+	return shell.Provision(
+		ctx,
+		ui,
+		communicator,
+		append(
+			steps,
+			shell.Step{
+				Name:     "Loading SSL certificate",
+				Commands: getCommandsLoadingCertificate(p.config.HomeDir, mailServerDomain, sslCertDestination, sslCertKeyDestination),
+			},
+		),
+		p.config.Ssl.SensitiveValues(keyPair)...,
+	)
 }
 
 func getDockerComposeFileTemplate() string {
@@ -109,17 +177,34 @@ services:
     `
 }
 
-func getCommands(homeDir string, domain string, sslCertDestination string, sslCertKeyDestination string) []string {
-	certsDir := filepath.Join(homeDir, fmt.Sprintf("docker-data/certbot/certs/live/%s", domain))
+// Returns the steps that install Docker and, unless mailserver.env has been uploaded already, download mailserver.env
+func getSteps(d *distro.Distro, dockerStep shell.Step, mailserverEnvUploaded bool) []shell.Step {
+	if mailserverEnvUploaded {
+		return []shell.Step{dockerStep}
+	}
 
-	return append(
-		shell.CommandsInstallingSudoLessDocker(),
-		[]string{
-			fmt.Sprintf("sudo mkdir -p %s", certsDir),
-			fmt.Sprintf("sudo mv %s %s", sslCertDestination, certsDir),
-			fmt.Sprintf("sudo mv %s %s", sslCertKeyDestination, certsDir),
+	return []shell.Step{
+		dockerStep,
+		{
+			Name:     "Downloading mailserver.env",
+			Commands: []string{d.InstallCommand("wget"), "wget \"https://raw.githubusercontent.com/docker-mailserver/docker-mailserver/master/mailserver.env\""},
+			Creates:  "mailserver.env",
+		},
+	}
+}
 
-			"wget \"https://raw.githubusercontent.com/docker-mailserver/docker-mailserver/master/mailserver.env\"",
-		}...,
-	)
+// Returns the certbot config directory, which the compose file mounts into the mail server container as
+// "/etc/letsencrypt"
+func getCertbotConfigDir(homeDir string) string {
+	return filepath.Join(homeDir, "docker-data/certbot/certs")
+}
+
+func getCommandsLoadingCertificate(homeDir string, domain string, sslCertDestination string, sslCertKeyDestination string) []string {
+	certsDir := filepath.Join(getCertbotConfigDir(homeDir), "live", domain)
+
+	return []string{
+		fmt.Sprintf("sudo mkdir -p %s", certsDir),
+		fmt.Sprintf("sudo mv %s %s", sslCertDestination, certsDir),
+		fmt.Sprintf("sudo mv %s %s", sslCertKeyDestination, certsDir),
+	}
 }
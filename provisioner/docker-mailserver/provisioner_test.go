@@ -0,0 +1,59 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package mailserver
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"strings"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"baseDomain":  "mycompany.com",
+		"sslCertMode": "self-signed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	composeFile := communicator.Uploaded("/home/ubuntu/compose.yaml")
+	if composeFile == nil || !strings.Contains(string(composeFile.Content), "hostname: mail.mycompany.com") {
+		t.Errorf("Expected compose file with the mail server hostname to be uploaded, got: %v", composeFile)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 3 {
+		t.Fatalf("Expected 3 steps to run, got %d", len(scripts))
+	}
+	if !strings.Contains(scripts[2], "sudo mkdir -p /home/ubuntu/docker-data/certbot/certs/live/mail.mycompany.com\n") {
+		t.Errorf("Expected certificate to be loaded for the mail server domain, got:\n%s", scripts[2])
+	}
+}
+
+func TestProvisionFailingStep(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"baseDomain":  "mycompany.com",
+		"sslCertMode": "self-signed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New().On("wget", 8, "")
+	err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil)
+
+	if err == nil || !strings.Contains(err.Error(), "Downloading mailserver.env") {
+		t.Errorf("Expected downloading mailserver.env to fail, got: %v", err)
+	}
+}
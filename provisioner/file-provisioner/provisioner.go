@@ -71,3 +71,9 @@ func Provision(ctx interpolate.Context, ui packersdk.Ui, communicator packersdk.
 
 	return nil
 }
+
+// ProvisionDirContents Uploads the contents of a local directory, instead of the directory itself, into the destination
+// directory in remote machine
+func ProvisionDirContents(ctx interpolate.Context, ui packersdk.Ui, communicator packersdk.Communicator, sourceDir string, destination string) error {
+	return Provision(ctx, ui, communicator, strings.TrimSuffix(sourceDir, "/")+"/", destination)
+}
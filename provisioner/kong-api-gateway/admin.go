@@ -0,0 +1,167 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// AdminHtpasswdDst The file in the image that Nginx checks the basic auth credentials of the Admin API and Manager against
+const AdminHtpasswdDst string = "/etc/nginx/kong-admin.htpasswd"
+
+// AdminClientCaDst The CA certificate in the image that Nginx verifies client certificates of the Admin API and Manager
+// against
+const AdminClientCaDst string = "/etc/nginx/kong-admin-ca.pem"
+
+const adminHtpasswdFilename string = "kong-admin.htpasswd"
+const adminClientCaFilename string = "kong-admin-ca.pem"
+
+// The prefixes of the password hashes that Nginx verifies: Apache MD5, SHA-256 crypt, SHA-512 crypt, bcrypt and SHA-1
+var passwordHashPrefixes = []string{"$apr1$", "$5$", "$6$", "$2a$", "$2b$", "$2y$", "{SHA}"}
+
+var basicAuthUserPattern = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// An endpoint of Kong that Nginx exposes over SSL on the domain
+type adminEndpoint struct {
+	ListenPort int
+	KongPort   int
+}
+
+// The Admin API and Manager, in the order of their server blocks
+var adminEndpoints = []adminEndpoint{{8444, 8001}, {8445, 8002}}
+
+func (c *Config) adminAccessControlled() bool {
+	return c.AdminLocalhostOnly || len(c.AdminAllowedCidrs) > 0 || c.AdminBasicAuthUser != "" || c.hasAdminClientCa()
+}
+
+func (c *Config) hasAdminClientCa() bool {
+	return c.AdminClientCaBase64 != "" || c.AdminClientCaFile != ""
+}
+
+func (c *Config) validateAdminAccess() []error {
+	var errs []error
+
+	if c.AdminLocalhostOnly && len(c.AdminAllowedCidrs) > 0 {
+		errs = append(errs, fmt.Errorf("adminAllowedCidrs: has no effect with adminLocalhostOnly"))
+	}
+	for _, cidr := range c.AdminAllowedCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			errs = append(errs, fmt.Errorf("adminAllowedCidrs: '%s' is neither a CIDR block nor an IP address", cidr))
+		}
+	}
+
+	if (c.AdminBasicAuthUser == "") != (c.AdminBasicAuthPasswordHash == "") {
+		errs = append(errs, fmt.Errorf("adminBasicAuthUser and adminBasicAuthPasswordHash are required together"))
+	}
+	if c.AdminBasicAuthUser != "" && !basicAuthUserPattern.MatchString(c.AdminBasicAuthUser) {
+		errs = append(errs, fmt.Errorf("adminBasicAuthUser: '%s' may contain only letters, digits, '.', '_', '@' and '-'", c.AdminBasicAuthUser))
+	}
+	if c.AdminBasicAuthPasswordHash != "" && !isPasswordHash(c.AdminBasicAuthPasswordHash) {
+		errs = append(errs, fmt.Errorf(
+			"adminBasicAuthPasswordHash: is not a password hash; generate one with 'openssl passwd -6' or "+
+				"'htpasswd -nbB <user> <password> | cut -d: -f2', instead of giving the password itself",
+		))
+	}
+
+	if c.AdminClientCaBase64 != "" && c.AdminClientCaFile != "" {
+		errs = append(errs, fmt.Errorf("adminClientCaBase64 and adminClientCaFile are mutually exclusive"))
+	} else if c.hasAdminClientCa() {
+		if _, err := c.loadAdminClientCa(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// Returns whether a value is a password hash that Nginx verifies, which never contains whitespace or a colon
+func isPasswordHash(value string) bool {
+	if strings.ContainsAny(value, " \t\n:") {
+		return false
+	}
+
+	for _, prefix := range passwordHashPrefixes {
+		if strings.HasPrefix(value, prefix) && len(value) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the PEM-encoded CA certificates that client certificates are verified against, after checking that they parse
+func (c *Config) loadAdminClientCa() (string, error) {
+	var ca string
+	if c.AdminClientCaFile != "" {
+		if err := validation.CheckSourcePath("adminClientCaFile", c.AdminClientCaFile); err != nil {
+			return "", err
+		}
+
+		content, err := os.ReadFile(c.AdminClientCaFile)
+		if err != nil {
+			return "", fmt.Errorf("adminClientCaFile: %s", err)
+		}
+		ca = string(content)
+	} else {
+		if err := validation.CheckBase64("adminClientCaBase64", c.AdminClientCaBase64); err != nil {
+			return "", err
+		}
+
+		decoded, err := ssl.DecodeBase64(c.AdminClientCaBase64)
+		if err != nil {
+			return "", fmt.Errorf("adminClientCaBase64: %s", err)
+		}
+		ca = decoded
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(ca)) {
+		return "", fmt.Errorf("client CA of the Admin API contains no PEM certificate")
+	}
+
+	return ca, nil
+}
+
+// Returns the htpasswd file that holds the basic auth credentials of the Admin API and Manager
+func (c *Config) getAdminHtpasswd() string {
+	return fmt.Sprintf("%s:%s\n", c.AdminBasicAuthUser, c.AdminBasicAuthPasswordHash)
+}
+
+// Returns the step that moves the uploaded client CA to where the Nginx config references it. It runs before Nginx is
+// installed, since Nginx loads the CA on start
+func getStepInstallingAdminClientCa(homeDir string) shell.Step {
+	return shell.Step{
+		Name: "Installing client CA of Kong Admin API",
+		Commands: []string{
+			fmt.Sprintf("sudo install -D -m 0644 -o root -g root %s %s", filepath.Join(homeDir, adminClientCaFilename), AdminClientCaDst),
+			fmt.Sprintf("rm -f %s", filepath.Join(homeDir, adminClientCaFilename)),
+		},
+	}
+}
+
+// Returns the step that moves the uploaded htpasswd file to where the Nginx config references it. The file is readable
+// by root and the group of the Nginx worker only, which is "www-data" or "nginx" depending on the distribution, so it
+// runs after Nginx is installed
+func getStepInstallingAdminHtpasswd(homeDir string) shell.Step {
+	return shell.Step{
+		Name: "Installing basic auth credentials of Kong Admin API",
+		Commands: []string{
+			`NGINX_USER=$(awk '$1 == "user" { sub(";", "", $2); print $2 }' /etc/nginx/nginx.conf)`,
+			fmt.Sprintf(
+				`sudo install -m 0640 -o root -g "$(id -gn "${NGINX_USER:-nginx}")" %s %s`,
+				filepath.Join(homeDir, adminHtpasswdFilename),
+				AdminHtpasswdDst,
+			),
+			fmt.Sprintf("rm -f %s", filepath.Join(homeDir, adminHtpasswdFilename)),
+		},
+	}
+}
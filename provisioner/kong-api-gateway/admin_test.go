@@ -0,0 +1,186 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPasswordHash = "$6$rounds=656000$YQKxqmnUzBCKyMpn$0I5pyTfsXJ4Y1W9iDfWv0lYLZmyH4Ld9lY6Zl9h/jXGkmlnkLyXGzCFLw1D6AwiFz8OnXYsYm5jN3bT4QIRl0."
+
+func newTestClientCa(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Kong Admin Clients"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestValidateAdminAccess(t *testing.T) {
+	clientCa := base64.StdEncoding.EncodeToString([]byte(newTestClientCa(t)))
+
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"no access control", Config{}, false},
+		{"localhost only", Config{AdminLocalhostOnly: true}, false},
+		{"allowed CIDRs", Config{AdminAllowedCidrs: []string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32"}}, false},
+		{"basic auth", Config{AdminBasicAuthUser: "ops", AdminBasicAuthPasswordHash: testPasswordHash}, false},
+		{"bcrypt hash", Config{AdminBasicAuthUser: "ops", AdminBasicAuthPasswordHash: "$2y$10$abcdefghijklmnopqrstuv"}, false},
+		{"client CA", Config{AdminClientCaBase64: clientCa}, false},
+		{"localhost only with CIDRs", Config{AdminLocalhostOnly: true, AdminAllowedCidrs: []string{"10.0.0.0/8"}}, true},
+		{"invalid CIDR", Config{AdminAllowedCidrs: []string{"10.0.0.0/33"}}, true},
+		{"user without hash", Config{AdminBasicAuthUser: "ops"}, true},
+		{"plain password", Config{AdminBasicAuthUser: "ops", AdminBasicAuthPasswordHash: "s3cr3t"}, true},
+		{"user with colon", Config{AdminBasicAuthUser: "ops:admin", AdminBasicAuthPasswordHash: testPasswordHash}, true},
+		{"client CA not PEM", Config{AdminClientCaBase64: base64.StdEncoding.EncodeToString([]byte("not a certificate"))}, true},
+		{"client CA twice", Config{AdminClientCaBase64: clientCa, AdminClientCaFile: "ca.pem"}, true},
+		{"missing client CA file", Config{AdminClientCaFile: "/does/not/exist/ca.pem"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateAdminAccess()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestGetNginxConfigWithAdminAccessControl(t *testing.T) {
+	data := []struct {
+		name             string
+		config           Config
+		expectedSnippets []string
+		excludedSnippets []string
+	}{
+		{
+			"public",
+			Config{},
+			[]string{"    listen 8444 ssl;\n", "    listen [::]:8445 ssl ipv6only=on;\n"},
+			[]string{"deny all;", "auth_basic", "ssl_verify_client"},
+		},
+		{
+			"localhost only",
+			Config{AdminLocalhostOnly: true},
+			[]string{"    listen 127.0.0.1:8444 ssl;\n", "    listen [::1]:8445 ssl;\n", "    listen 443 ssl;\n"},
+			[]string{"    listen 8444 ssl;\n", "    listen [::]:8445 ssl ipv6only=on;\n"},
+		},
+		{
+			"allowed CIDRs, basic auth and client CA",
+			Config{
+				AdminAllowedCidrs:          []string{"10.0.0.0/8", "203.0.113.7"},
+				AdminBasicAuthUser:         "ops",
+				AdminBasicAuthPasswordHash: testPasswordHash,
+				AdminClientCaFile:          "ca.pem",
+			},
+			[]string{
+				"    allow 10.0.0.0/8;\n    allow 203.0.113.7;\n    deny all;\n",
+				"    auth_basic_user_file /etc/nginx/kong-admin.htpasswd;\n",
+				"    ssl_client_certificate /etc/nginx/kong-admin-ca.pem;\n    ssl_verify_client on;\n",
+			},
+			nil,
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			d.config.KongApiGatewayDomain = "api.mycompany.com"
+			nginxConfig := getNginxConfig(d.config)
+
+			proxyServer := nginxConfig[:strings.Index(nginxConfig, "proxy_pass http://localhost:8001;")]
+			proxyServer = proxyServer[:strings.LastIndex(proxyServer, "server {")]
+			if strings.Contains(proxyServer, "deny all;") || strings.Contains(proxyServer, "auth_basic") || strings.Contains(proxyServer, "ssl_verify_client") {
+				t.Errorf("Expected Kong proxy to stay public, got:\n%s", proxyServer)
+			}
+
+			for _, snippet := range d.expectedSnippets {
+				if !strings.Contains(nginxConfig, snippet) {
+					t.Errorf("Expected '%s' in Nginx config:\n%s", snippet, nginxConfig)
+				}
+			}
+			for _, snippet := range d.excludedSnippets {
+				if strings.Contains(nginxConfig, snippet) {
+					t.Errorf("Expected no '%s' in Nginx config:\n%s", snippet, nginxConfig)
+				}
+			}
+		})
+	}
+}
+
+func TestProvisionAdminAccessControl(t *testing.T) {
+	clientCaFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(clientCaFile, []byte(newTestClientCa(t)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"kongApiGatewayDomain":       "api.mycompany.com",
+		"sslCertMode":                "self-signed",
+		"adminBasicAuthUser":         "ops",
+		"adminBasicAuthPasswordHash": testPasswordHash,
+		"adminClientCaFile":          clientCaFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	htpasswd := communicator.Uploaded("/home/ubuntu/kong-admin.htpasswd")
+	if htpasswd == nil || string(htpasswd.Content) != "ops:"+testPasswordHash+"\n" {
+		t.Errorf("Expected htpasswd file to be uploaded, got: %v", htpasswd)
+	}
+	if communicator.Uploaded("/home/ubuntu/kong-admin-ca.pem") == nil {
+		t.Error("Expected client CA to be uploaded")
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 6 {
+		t.Fatalf("Expected 6 steps to run, got %d", len(scripts))
+	}
+	if !strings.Contains(scripts[2], "sudo install -D -m 0644 -o root -g root /home/ubuntu/kong-admin-ca.pem /etc/nginx/kong-admin-ca.pem\n") {
+		t.Errorf("Expected client CA to be installed before Nginx, got:\n%s", scripts[2])
+	}
+	if !strings.Contains(scripts[5], "/home/ubuntu/kong-admin.htpasswd /etc/nginx/kong-admin.htpasswd\n") {
+		t.Errorf("Expected htpasswd file to be installed after Nginx, got:\n%s", scripts[5])
+	}
+}
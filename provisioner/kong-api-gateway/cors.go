@@ -0,0 +1,252 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CorsModeNginx Nginx answers preflight requests and adds the CORS headers to the responses of Kong
+	CorsModeNginx string = "nginx"
+	// CorsModeKong The cors plugin of Kong is enabled globally in the declarative configuration instead
+	CorsModeKong string = "kong"
+	// CorsModeDisabled No CORS headers are added, so that browsers allow same-origin requests only
+	CorsModeDisabled string = "disabled"
+)
+
+// DefaultCorsAllowedMethods The methods that cross-origin requests may use, unless others are configured
+var DefaultCorsAllowedMethods = []string{"GET", "POST", "OPTIONS", "HEAD"}
+
+// DefaultCorsAllowedHeaders The request headers that cross-origin requests may send, unless others are configured
+var DefaultCorsAllowedHeaders = []string{"Authorization", "Origin", "X-Requested-With", "Content-Type", "Accept"}
+
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+func (c *Config) corsMode() string {
+	if c.CorsMode == "" {
+		return CorsModeNginx
+	}
+
+	return c.CorsMode
+}
+
+// Returns the allowed origins, which default to any origin
+func (c *Config) corsAllowedOrigins() []string {
+	if len(c.CorsAllowedOrigins) == 0 {
+		return []string{"*"}
+	}
+
+	return c.CorsAllowedOrigins
+}
+
+func (c *Config) corsAllowedMethods() []string {
+	if len(c.CorsAllowedMethods) == 0 {
+		return DefaultCorsAllowedMethods
+	}
+
+	return c.CorsAllowedMethods
+}
+
+func (c *Config) corsAllowedHeaders() []string {
+	if len(c.CorsAllowedHeaders) == 0 {
+		return DefaultCorsAllowedHeaders
+	}
+
+	return c.CorsAllowedHeaders
+}
+
+// Returns whether any origin is allowed, in which case the Origin header of requests is not checked
+func (c *Config) corsAnyOrigin() bool {
+	origins := c.corsAllowedOrigins()
+	return len(origins) == 1 && origins[0] == "*"
+}
+
+func (c *Config) validateCors() []error {
+	var errs []error
+
+	switch c.corsMode() {
+	case CorsModeNginx, CorsModeKong:
+	case CorsModeDisabled:
+		if len(c.CorsAllowedOrigins) > 0 || len(c.CorsAllowedMethods) > 0 || len(c.CorsAllowedHeaders) > 0 ||
+			len(c.CorsExposedHeaders) > 0 || c.CorsAllowCredentials || c.CorsMaxAgeSeconds != 0 {
+			errs = append(errs, fmt.Errorf("corsMode: CORS settings have no effect when CORS is disabled"))
+		}
+		return errs
+	default:
+		return []error{fmt.Errorf("corsMode: '%s' is not one of 'nginx', 'kong' and 'disabled'", c.CorsMode)}
+	}
+
+	if c.corsMode() == CorsModeKong && c.DeclarativeConfigFile != "" {
+		errs = append(errs, fmt.Errorf("corsMode: the cors plugin cannot be added to declarativeConfigFile; enable it in the file instead"))
+	}
+	for _, plugin := range c.Plugins {
+		if plugin.Name == "cors" {
+			errs = append(errs, fmt.Errorf("plugins: the cors plugin would duplicate the CORS headers of corsMode '%s'; set corsMode to 'disabled' to configure it yourself", c.corsMode()))
+		}
+	}
+
+	for _, origin := range c.CorsAllowedOrigins {
+		if err := validateCorsOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("corsAllowedOrigins: %s", err))
+		}
+	}
+	if len(c.CorsAllowedOrigins) > 1 && containsString(c.CorsAllowedOrigins, "*") {
+		errs = append(errs, fmt.Errorf("corsAllowedOrigins: '*' allows any origin and cannot be combined with others"))
+	}
+	if c.CorsAllowCredentials && c.corsAnyOrigin() {
+		errs = append(errs, fmt.Errorf("corsAllowCredentials: browsers reject credentials for any origin; list the allowed origins in corsAllowedOrigins"))
+	}
+
+	for _, method := range c.CorsAllowedMethods {
+		if !methodPattern.MatchString(method) {
+			errs = append(errs, fmt.Errorf("corsAllowedMethods: '%s' is not an upper-case HTTP method", method))
+		}
+	}
+	for _, header := range c.CorsAllowedHeaders {
+		if !headerNamePattern.MatchString(header) {
+			errs = append(errs, fmt.Errorf("corsAllowedHeaders: '%s' is not a header name", header))
+		}
+	}
+	for _, header := range c.CorsExposedHeaders {
+		if !headerNamePattern.MatchString(header) {
+			errs = append(errs, fmt.Errorf("corsExposedHeaders: '%s' is not a header name", header))
+		}
+	}
+	if c.CorsMaxAgeSeconds < 0 {
+		errs = append(errs, fmt.Errorf("corsMaxAgeSeconds: %d is negative", c.CorsMaxAgeSeconds))
+	}
+
+	return errs
+}
+
+// Checks that an origin is "*", an origin such as "https://app.mycompany.com" with an optional port, or a regular
+// expression starting with "~". The host of an origin must be a domain name, since origins are quoted keys of a map in
+// the Nginx config
+func validateCorsOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	if strings.HasPrefix(origin, "~") {
+		pattern := strings.TrimPrefix(origin, "~")
+		if pattern == "" || strings.ContainsAny(pattern, "\" \t\n;{}") {
+			return fmt.Errorf("'%s' is not a regular expression without quotes, whitespace, ';' and braces", origin)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("'%s' is not a valid regular expression: %s", origin, err)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" ||
+		u.Fragment != "" || u.User != nil || strings.HasSuffix(u.Host, ":") ||
+		validation.CheckDomain("origin", u.Hostname()) != nil || !isPort(u.Port()) {
+		return fmt.Errorf("'%s' is not an origin such as 'https://app.mycompany.com'", origin)
+	}
+
+	return nil
+}
+
+// Returns whether a port of an origin is either omitted or between 1 and 65535
+func isPort(port string) bool {
+	if port == "" {
+		return true
+	}
+
+	number, err := strconv.Atoi(port)
+	return err == nil && number >= 1 && number <= 65535
+}
+
+// Returns the plugins enabled globally, including the cors plugin of Kong in "kong" mode
+func (c *Config) plugins() []Plugin {
+	if c.corsMode() != CorsModeKong {
+		return c.Plugins
+	}
+
+	return append(append([]Plugin{}, c.Plugins...), c.getCorsPlugin())
+}
+
+// Returns the cors plugin of Kong configured with the CORS settings. Kong matches origins made of letters, digits, '.',
+// ':', '/' and '-' literally and any other as a regular expression
+func (c *Config) getCorsPlugin() Plugin {
+	var origins []string
+	for _, origin := range c.corsAllowedOrigins() {
+		origins = append(origins, strings.TrimPrefix(origin, "~"))
+	}
+
+	config := map[string]interface{}{
+		"origins":     origins,
+		"methods":     c.corsAllowedMethods(),
+		"headers":     c.corsAllowedHeaders(),
+		"credentials": c.CorsAllowCredentials,
+	}
+	if len(c.CorsExposedHeaders) > 0 {
+		config["exposed_headers"] = c.CorsExposedHeaders
+	}
+	if c.CorsMaxAgeSeconds > 0 {
+		config["max_age"] = c.CorsMaxAgeSeconds
+	}
+
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return Plugin{Name: "cors", Config: string(encoded)}
+}
+
+// The CORS settings rendered into the Nginx config
+type nginxCors struct {
+	// Origins The allowed origins, which are the quoted keys of the map of the Origin header
+	Origins          []string
+	AllowOrigin      string
+	AllowMethods     string
+	AllowHeaders     string
+	ExposeHeaders    string
+	AllowCredentials bool
+	MaxAgeSeconds    int
+}
+
+// Returns the CORS settings of the Nginx config, or nil unless Nginx handles CORS. For any origin other than "*", the
+// Origin header of a request is echoed only if it is allowed, through a map from the header to the allowed origin
+func (c *Config) getNginxCors() *nginxCors {
+	if c.corsMode() != CorsModeNginx {
+		return nil
+	}
+
+	cors := &nginxCors{
+		AllowOrigin:      "'*'",
+		AllowMethods:     strings.Join(c.corsAllowedMethods(), ", "),
+		AllowHeaders:     strings.Join(c.corsAllowedHeaders(), ", "),
+		ExposeHeaders:    strings.Join(c.CorsExposedHeaders, ", "),
+		AllowCredentials: c.CorsAllowCredentials,
+		MaxAgeSeconds:    c.CorsMaxAgeSeconds,
+	}
+	if !c.corsAnyOrigin() {
+		cors.AllowOrigin = "$kong_cors_origin"
+		for _, origin := range c.CorsAllowedOrigins {
+			cors.Origins = append(cors.Origins, `"`+origin+`"`)
+		}
+	}
+
+	return cors
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
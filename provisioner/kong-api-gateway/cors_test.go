@@ -0,0 +1,166 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateCors(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"defaults", Config{}, false},
+		{
+			"origins with credentials",
+			Config{
+				CorsAllowedOrigins:   []string{"https://app.mycompany.com", `~^https://[a-z0-9-]+\.mycompany\.com$`},
+				CorsAllowCredentials: true,
+				CorsExposedHeaders:   []string{"X-Request-ID"},
+				CorsMaxAgeSeconds:    600,
+			},
+			false,
+		},
+		{"kong mode", Config{CorsMode: "kong", CorsAllowedOrigins: []string{"http://localhost:3000"}}, false},
+		{"disabled", Config{CorsMode: "disabled"}, false},
+		{"cors plugin when disabled", Config{CorsMode: "disabled", Plugins: []Plugin{{Name: "cors"}}}, false},
+		{"unknown mode", Config{CorsMode: "apache"}, true},
+		{"settings when disabled", Config{CorsMode: "disabled", CorsAllowedOrigins: []string{"https://app.mycompany.com"}}, true},
+		{"cors plugin in nginx mode", Config{Plugins: []Plugin{{Name: "cors"}}}, true},
+		{"kong mode with file", Config{CorsMode: "kong", DeclarativeConfigFile: "kong.yaml"}, true},
+		{"origin with path", Config{CorsAllowedOrigins: []string{"https://app.mycompany.com/"}}, true},
+		{"origin without scheme", Config{CorsAllowedOrigins: []string{"app.mycompany.com"}}, true},
+		{"origin with port", Config{CorsAllowedOrigins: []string{"https://app.mycompany.com:8443"}}, false},
+		{"quote in origin", Config{CorsAllowedOrigins: []string{`https://app";mycompany.com`}}, true},
+		{"invalid port", Config{CorsAllowedOrigins: []string{"https://app.mycompany.com:70000"}}, true},
+		{"empty port", Config{CorsAllowedOrigins: []string{"https://app.mycompany.com:"}}, true},
+		{"invalid regular expression", Config{CorsAllowedOrigins: []string{"~^https://(app"}}, true},
+		{"quote in regular expression", Config{CorsAllowedOrigins: []string{`~^https://"app`}}, true},
+		{"any origin among others", Config{CorsAllowedOrigins: []string{"*", "https://app.mycompany.com"}}, true},
+		{"credentials for any origin", Config{CorsAllowCredentials: true}, true},
+		{"lower-case method", Config{CorsAllowedMethods: []string{"get"}}, true},
+		{"quote in header", Config{CorsAllowedHeaders: []string{"X-Token'"}}, true},
+		{"negative max age", Config{CorsMaxAgeSeconds: -1}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateCors()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestGetNginxConfigWithCors(t *testing.T) {
+	data := []struct {
+		name             string
+		config           Config
+		expectedSnippets []string
+		excludedSnippets []string
+	}{
+		{
+			"defaults",
+			Config{},
+			[]string{
+				"            add_header 'Access-Control-Allow-Origin' '*';\n",
+				"            add_header 'Access-Control-Allow-Methods' 'GET, POST, OPTIONS, HEAD';\n",
+				"            add_header 'Access-Control-Allow-Headers' 'Authorization, Origin, X-Requested-With, Content-Type, Accept';\n",
+				"        add_header 'Access-Control-Allow-Origin' '*' always;\n",
+			},
+			[]string{"map $http_origin", "Vary", "Access-Control-Allow-Credentials"},
+		},
+		{
+			"origins with credentials",
+			Config{
+				CorsAllowedOrigins:   []string{"https://app.mycompany.com", `~^https://[a-z0-9-]+\.mycompany\.com$`},
+				CorsAllowedMethods:   []string{"GET", "PUT"},
+				CorsAllowCredentials: true,
+				CorsExposedHeaders:   []string{"X-Request-ID", "RateLimit-Remaining"},
+				CorsMaxAgeSeconds:    600,
+			},
+			[]string{
+				"map $http_origin $kong_cors_origin {\n    default \"\";\n" +
+					"    \"https://app.mycompany.com\" $http_origin;\n" +
+					"    \"~^https://[a-z0-9-]+\\.mycompany\\.com$\" $http_origin;\n}\n",
+				"            add_header 'Access-Control-Allow-Origin' $kong_cors_origin;\n",
+				"            add_header 'Access-Control-Allow-Methods' 'GET, PUT';\n",
+				"            add_header 'Access-Control-Max-Age' '600';\n",
+				"        add_header 'Access-Control-Allow-Credentials' 'true' always;\n",
+				"        add_header 'Access-Control-Expose-Headers' 'X-Request-ID, RateLimit-Remaining' always;\n",
+				"        add_header 'Vary' 'Origin' always;\n",
+			},
+			[]string{"'*'"},
+		},
+		{"kong mode", Config{CorsMode: "kong"}, nil, []string{"Access-Control-", "map $http_origin"}},
+		{"disabled", Config{CorsMode: "disabled"}, nil, []string{"Access-Control-", "map $http_origin"}},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			d.config.KongApiGatewayDomain = "api.mycompany.com"
+			nginxConfig := getNginxConfig(d.config)
+
+			if !strings.Contains(nginxConfig, "        proxy_pass http://localhost:8000;\n") {
+				t.Errorf("Expected Kong proxy to be proxied to, got:\n%s", nginxConfig)
+			}
+			for _, snippet := range d.expectedSnippets {
+				if !strings.Contains(nginxConfig, snippet) {
+					t.Errorf("Expected '%s' in Nginx config:\n%s", snippet, nginxConfig)
+				}
+			}
+			for _, snippet := range d.excludedSnippets {
+				if strings.Contains(nginxConfig, snippet) {
+					t.Errorf("Expected no '%s' in Nginx config:\n%s", snippet, nginxConfig)
+				}
+			}
+		})
+	}
+}
+
+func TestCorsPlugin(t *testing.T) {
+	config := Config{
+		CorsMode:             "kong",
+		CorsAllowedOrigins:   []string{"https://app.mycompany.com", `~^https://[a-z0-9-]+\.mycompany\.com$`},
+		CorsAllowCredentials: true,
+		CorsMaxAgeSeconds:    600,
+		Plugins:              []Plugin{{Name: "correlation-id"}},
+	}
+
+	plugins := config.plugins()
+	if len(plugins) != 2 || plugins[0].Name != "correlation-id" || plugins[1].Name != "cors" {
+		t.Fatalf("Expected cors plugin to be enabled after the configured plugins, got: %v", plugins)
+	}
+
+	var corsConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(plugins[1].Config), &corsConfig); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"origins":     []interface{}{"https://app.mycompany.com", `^https://[a-z0-9-]+\.mycompany\.com$`},
+		"methods":     []interface{}{"GET", "POST", "OPTIONS", "HEAD"},
+		"headers":     []interface{}{"Authorization", "Origin", "X-Requested-With", "Content-Type", "Accept"},
+		"credentials": true,
+		"max_age":     float64(600),
+	}
+	if !reflect.DeepEqual(expected, corsConfig) {
+		t.Errorf("Expected cors plugin config %v, got: %v", expected, corsConfig)
+	}
+
+	if !config.hasDeclarativeConfig() {
+		t.Error("Expected declarative config to be loaded for the cors plugin")
+	}
+	if errs := config.validateDeclarativeConfig(); len(errs) > 0 {
+		t.Errorf("Expected declarative config with cors plugin to be valid, got: %v", errs)
+	}
+	if len(config.Plugins) != 1 {
+		t.Errorf("Expected configured plugins to be left untouched, got: %v", config.Plugins)
+	}
+}
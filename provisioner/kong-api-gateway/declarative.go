@@ -0,0 +1,387 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DeclarativeConfigFormatVersion The format version of the generated declarative configuration
+const DeclarativeConfigFormatVersion string = "3.0"
+
+// DeclarativeConfigPath The path, relative to the home directory, of the declarative configuration that the compose
+// file of docker-kong loads into Kong running in DB-less mode
+const DeclarativeConfigPath string = "docker-kong/compose/config/kong.yaml"
+
+const stagedDeclarativeConfigFilename string = "kong.yaml"
+
+var formatVersions = map[string]bool{"1.1": true, "2.1": true, "3.0": true}
+
+var protocols = map[string]bool{
+	"http": true, "https": true, "grpc": true, "grpcs": true, "tcp": true, "tls": true, "tls_passthrough": true,
+	"udp": true, "ws": true, "wss": true,
+}
+
+var kongNamePattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+var methodPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// Service An upstream API that Kong proxies to
+type Service struct {
+	// Name The name of the service
+	Name string `mapstructure:"name" required:"true" yaml:"name"`
+	// Url The URL of the upstream API, such as "http://10.0.0.5:8080/api"; an alternative to Host
+	Url string `mapstructure:"url" required:"false" yaml:"url,omitempty"`
+	// Host The host of the upstream API; an alternative to Url
+	Host string `mapstructure:"host" required:"false" yaml:"host,omitempty"`
+	// Port The port of the upstream API along with Host. Default to 80
+	Port int `mapstructure:"port" required:"false" yaml:"port,omitempty"`
+	// Protocol The protocol of the upstream API along with Host. Default to "http"
+	Protocol string `mapstructure:"protocol" required:"false" yaml:"protocol,omitempty"`
+	// Path The path prefix of the upstream API along with Host
+	Path string `mapstructure:"path" required:"false" yaml:"path,omitempty"`
+	// Routes The routes through which clients reach the service
+	Routes []Route `mapstructure:"routes" required:"false" yaml:"routes,omitempty"`
+	// Plugins The plugins that run on every request to the service
+	Plugins []Plugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty"`
+}
+
+// Route A set of rules matching client requests to a service
+type Route struct {
+	// Name The name of the route
+	Name string `mapstructure:"name" required:"false" yaml:"name,omitempty"`
+	// Paths The path prefixes, or regular expressions starting with "~", that the route matches
+	Paths []string `mapstructure:"paths" required:"false" yaml:"paths,omitempty"`
+	// Hosts The host names that the route matches
+	Hosts []string `mapstructure:"hosts" required:"false" yaml:"hosts,omitempty"`
+	// Methods The HTTP methods that the route matches
+	Methods []string `mapstructure:"methods" required:"false" yaml:"methods,omitempty"`
+	// StripPath Whether the matched path prefix is removed before proxying. Default to true
+	StripPath *bool `mapstructure:"stripPath" required:"false" yaml:"strip_path,omitempty"`
+	// PreserveHost Whether the Host header of the client is sent to the service. Default to false
+	PreserveHost bool `mapstructure:"preserveHost" required:"false" yaml:"preserve_host,omitempty"`
+	// Plugins The plugins that run on every request matching the route
+	Plugins []Plugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty"`
+}
+
+// Consumer A client of the APIs, which plugins identify and apply per-client settings to
+type Consumer struct {
+	// Username The unique name of the consumer; required unless CustomId is given
+	Username string `mapstructure:"username" required:"false" yaml:"username,omitempty"`
+	// CustomId The ID of the consumer in another system; required unless Username is given
+	CustomId string `mapstructure:"customId" required:"false" yaml:"custom_id,omitempty"`
+	// Plugins The plugins that run on every request of the consumer
+	Plugins []Plugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty"`
+}
+
+// Plugin A Kong plugin, such as "rate-limiting" or "key-auth"
+type Plugin struct {
+	// Name The name of the plugin
+	Name string `mapstructure:"name" required:"true"`
+	// Config The configuration of the plugin as a JSON object, such as `jsonencode({ minute = 60 })`
+	Config string `mapstructure:"config" required:"false"`
+}
+
+// The document of the declarative configuration generated from the services, consumers and plugins of the provisioner
+type declarativeConfig struct {
+	FormatVersion string     `yaml:"_format_version"`
+	Services      []Service  `yaml:"services,omitempty"`
+	Consumers     []Consumer `yaml:"consumers,omitempty"`
+	Plugins       []Plugin   `yaml:"plugins,omitempty"`
+}
+
+// A declarative configuration file, such as one dumped by decK. Only its format version is checked, since files may use
+// any entity and field of Kong, such as plugins scoped to a service or stream routes matching on SNIs, which are left
+// for Kong to validate
+type declarativeConfigFile struct {
+	FormatVersion string `yaml:"_format_version"`
+}
+
+type yamlPlugin struct {
+	Name   string                 `yaml:"name"`
+	Config map[string]interface{} `yaml:"config,omitempty"`
+}
+
+// MarshalYAML Writes the JSON configuration of the plugin as a YAML mapping
+func (p Plugin) MarshalYAML() (interface{}, error) {
+	config, err := p.decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return yamlPlugin{p.Name, config}, nil
+}
+
+// UnmarshalYAML Reads the configuration of the plugin from a YAML mapping
+func (p *Plugin) UnmarshalYAML(value *yaml.Node) error {
+	var plugin yamlPlugin
+	if err := value.Decode(&plugin); err != nil {
+		return err
+	}
+
+	p.Name = plugin.Name
+	p.Config = ""
+	if plugin.Config != nil {
+		config, err := json.Marshal(plugin.Config)
+		if err != nil {
+			return fmt.Errorf("config of plugin '%s' is not representable as JSON: %s", plugin.Name, err)
+		}
+		p.Config = string(config)
+	}
+
+	return nil
+}
+
+func (p *Plugin) decodeConfig() (map[string]interface{}, error) {
+	if p.Config == "" {
+		return nil, nil
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(p.Config), &config); err != nil || config == nil {
+		return nil, fmt.Errorf("'%s' is not a JSON object", p.Config)
+	}
+
+	return config, nil
+}
+
+func (c *Config) hasDeclarativeConfig() bool {
+	return c.DeclarativeConfigFile != "" || len(c.Services) > 0 || len(c.Consumers) > 0 || len(c.plugins()) > 0
+}
+
+// Returns the declarative configuration that is loaded into Kong, which is either the configured file as is or
+// generated from the services, consumers and plugins, including the cors plugin in "kong" CORS mode
+func (c *Config) getDeclarativeConfig() (string, error) {
+	if c.DeclarativeConfigFile != "" {
+		content, err := os.ReadFile(c.DeclarativeConfigFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading declarative config file '%s': %s", c.DeclarativeConfigFile, err)
+		}
+
+		return string(content), nil
+	}
+
+	content, err := yaml.Marshal(declarativeConfig{DeclarativeConfigFormatVersion, c.Services, c.Consumers, c.plugins()})
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func (c *Config) validateDeclarativeConfig() []error {
+	if c.DeclarativeConfigFile == "" {
+		return declarativeConfig{DeclarativeConfigFormatVersion, c.Services, c.Consumers, c.plugins()}.validate("")
+	}
+
+	if len(c.Services) > 0 || len(c.Consumers) > 0 || len(c.Plugins) > 0 {
+		return []error{fmt.Errorf("declarativeConfigFile: services, consumers and plugins are defined by the file instead")}
+	}
+
+	if err := validation.CheckSourcePath("declarativeConfigFile", c.DeclarativeConfigFile); err != nil {
+		return []error{err}
+	}
+
+	content, err := c.getDeclarativeConfig()
+	if err != nil {
+		return []error{fmt.Errorf("declarativeConfigFile: %s", err)}
+	}
+
+	var document declarativeConfigFile
+	if err = yaml.Unmarshal([]byte(content), &document); err != nil {
+		return []error{fmt.Errorf("declarativeConfigFile: '%s' is not a valid declarative config: %s", c.DeclarativeConfigFile, err)}
+	}
+	if !formatVersions[document.FormatVersion] {
+		return []error{fmt.Errorf("declarativeConfigFile: _format_version: '%s' is not one of 1.1, 2.1 and 3.0", document.FormatVersion)}
+	}
+
+	return nil
+}
+
+// Checks the entities of the document, prefixing every error with the given prefix. Field names follow the
+// configuration of the provisioner, so that they point at the offending block
+func (d declarativeConfig) validate(prefix string) []error {
+	var errs []error
+
+	if !formatVersions[d.FormatVersion] {
+		errs = append(errs, fmt.Errorf("_format_version: '%s' is not one of 1.1, 2.1 and 3.0", d.FormatVersion))
+	}
+
+	serviceNames := make(map[string]bool)
+	routeNames := make(map[string]bool)
+	for i, service := range d.Services {
+		field := fmt.Sprintf("services[%d]", i)
+		errs = append(errs, checkRequired(field, &service)...)
+		errs = append(errs, checkName(field+".name", service.Name)...)
+		if service.Name != "" && serviceNames[service.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: '%s' is defined more than once", field, service.Name))
+		}
+		serviceNames[service.Name] = true
+
+		errs = append(errs, service.validateUpstream(field)...)
+
+		for j, route := range service.Routes {
+			routeField := fmt.Sprintf("%s.routes[%d]", field, j)
+			errs = append(errs, route.validate(routeField)...)
+			if route.Name != "" && routeNames[route.Name] {
+				errs = append(errs, fmt.Errorf("%s.name: '%s' is defined more than once", routeField, route.Name))
+			}
+			routeNames[route.Name] = true
+		}
+
+		errs = append(errs, validatePlugins(field+".plugins", service.Plugins)...)
+	}
+
+	consumerIds := make(map[string]bool)
+	for i, consumer := range d.Consumers {
+		field := fmt.Sprintf("consumers[%d]", i)
+		if consumer.Username == "" && consumer.CustomId == "" {
+			errs = append(errs, fmt.Errorf("%s: username or customId is required", field))
+		}
+		for _, id := range []string{"username:" + consumer.Username, "customId:" + consumer.CustomId} {
+			if !strings.HasSuffix(id, ":") && consumerIds[id] {
+				errs = append(errs, fmt.Errorf("%s: '%s' is defined more than once", field, id))
+			}
+			consumerIds[id] = true
+		}
+
+		errs = append(errs, validatePlugins(field+".plugins", consumer.Plugins)...)
+	}
+
+	errs = append(errs, validatePlugins("plugins", d.Plugins)...)
+
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s%s", prefix, err)
+	}
+
+	return errs
+}
+
+// Checks that the service names its upstream either by URL or by host, port, protocol and path
+func (s *Service) validateUpstream(field string) []error {
+	var errs []error
+
+	if (s.Url == "") == (s.Host == "") {
+		return []error{fmt.Errorf("%s: exactly one of url and host is required", field)}
+	}
+
+	if s.Url != "" {
+		if s.Port != 0 || s.Protocol != "" || s.Path != "" {
+			errs = append(errs, fmt.Errorf("%s: port, protocol and path are part of url already", field))
+		}
+
+		u, err := url.Parse(s.Url)
+		if err != nil || u.Hostname() == "" {
+			return append(errs, fmt.Errorf("%s.url: '%s' is not an absolute URL", field, s.Url))
+		}
+		if !protocols[u.Scheme] {
+			errs = append(errs, fmt.Errorf("%s.url: '%s' is not a protocol that Kong proxies", field, u.Scheme))
+		}
+
+		return errs
+	}
+
+	if s.Port < 0 || s.Port > 65535 {
+		errs = append(errs, fmt.Errorf("%s.port: %d is not a valid port", field, s.Port))
+	}
+	if s.Protocol != "" && !protocols[s.Protocol] {
+		errs = append(errs, fmt.Errorf("%s.protocol: '%s' is not a protocol that Kong proxies", field, s.Protocol))
+	}
+	if s.Path != "" && !strings.HasPrefix(s.Path, "/") {
+		errs = append(errs, fmt.Errorf("%s.path: '%s' does not start with '/'", field, s.Path))
+	}
+
+	return errs
+}
+
+func (r *Route) validate(field string) []error {
+	var errs []error
+
+	errs = append(errs, checkName(field+".name", r.Name)...)
+
+	if len(r.Paths) == 0 && len(r.Hosts) == 0 && len(r.Methods) == 0 {
+		errs = append(errs, fmt.Errorf("%s: at least one of paths, hosts and methods is required", field))
+	}
+	for _, path := range r.Paths {
+		if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "~/") {
+			errs = append(errs, fmt.Errorf("%s.paths: '%s' starts with neither '/' nor '~/'", field, path))
+		}
+	}
+	for _, host := range r.Hosts {
+		if err := validation.CheckDomain(field+".hosts", strings.TrimPrefix(host, "*.")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, method := range r.Methods {
+		if !methodPattern.MatchString(method) {
+			errs = append(errs, fmt.Errorf("%s.methods: '%s' is not an upper-case HTTP method", field, method))
+		}
+	}
+
+	return append(errs, validatePlugins(field+".plugins", r.Plugins)...)
+}
+
+// Checks the plugins of an entity, which Kong runs at most one instance of per plugin name
+func validatePlugins(field string, plugins []Plugin) []error {
+	var errs []error
+
+	names := make(map[string]bool)
+	for i, plugin := range plugins {
+		pluginField := fmt.Sprintf("%s[%d]", field, i)
+		errs = append(errs, checkRequired(pluginField, &plugin)...)
+
+		if plugin.Name != "" && !pluginNamePattern.MatchString(plugin.Name) {
+			errs = append(errs, fmt.Errorf("%s.name: '%s' is not a plugin name such as 'rate-limiting'", pluginField, plugin.Name))
+		}
+		if plugin.Name != "" && names[plugin.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: '%s' is enabled more than once", pluginField, plugin.Name))
+		}
+		names[plugin.Name] = true
+
+		if _, err := plugin.decodeConfig(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.config: %s", pluginField, err))
+		}
+	}
+
+	return errs
+}
+
+// Returns the step that moves the uploaded declarative configuration to where docker-kong loads it from
+func getStepInstallingDeclarativeConfig(homeDir string) shell.Step {
+	dst := filepath.Join(homeDir, DeclarativeConfigPath)
+
+	return shell.Step{
+		Name: "Installing declarative Kong configuration",
+		Commands: []string{
+			fmt.Sprintf("mkdir -p %s", filepath.Dir(dst)),
+			fmt.Sprintf("mv %s %s", filepath.Join(homeDir, stagedDeclarativeConfigFilename), dst),
+		},
+	}
+}
+
+func checkRequired(field string, block interface{}) []error {
+	var errs []error
+	for _, err := range validation.CheckRequired(block) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, err))
+	}
+
+	return errs
+}
+
+func checkName(field string, name string) []error {
+	if name != "" && !kongNamePattern.MatchString(name) {
+		return []error{fmt.Errorf("%s: '%s' may contain only letters, digits, '.', '_', '~' and '-'", field, name)}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testDeclarativeConfig() Config {
+	stripPath := false
+	return Config{
+		Services: []Service{{
+			Name: "orders",
+			Url:  "http://10.0.0.5:8080/api",
+			Routes: []Route{{
+				Name:      "orders",
+				Paths:     []string{"/orders"},
+				Methods:   []string{"GET", "POST"},
+				StripPath: &stripPath,
+				Plugins:   []Plugin{{Name: "key-auth"}},
+			}},
+			Plugins: []Plugin{{Name: "rate-limiting", Config: `{"minute": 60, "policy": "local"}`}},
+		}},
+		Consumers: []Consumer{{Username: "mobile-app"}},
+		Plugins:   []Plugin{{Name: "correlation-id", Config: `{"header_name": "X-Request-ID"}`}},
+	}
+}
+
+func TestGetDeclarativeConfig(t *testing.T) {
+	config := testDeclarativeConfig()
+
+	content, err := config.getDeclarativeConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document map[string]interface{}
+	if err = yaml.Unmarshal([]byte(content), &document); err != nil {
+		t.Fatalf("Expected valid YAML, got: %v\n%s", err, content)
+	}
+
+	expected := map[string]interface{}{
+		"_format_version": "3.0",
+		"services": []interface{}{map[string]interface{}{
+			"name": "orders",
+			"url":  "http://10.0.0.5:8080/api",
+			"routes": []interface{}{map[string]interface{}{
+				"name":       "orders",
+				"paths":      []interface{}{"/orders"},
+				"methods":    []interface{}{"GET", "POST"},
+				"strip_path": false,
+				"plugins":    []interface{}{map[string]interface{}{"name": "key-auth"}},
+			}},
+			"plugins": []interface{}{map[string]interface{}{
+				"name":   "rate-limiting",
+				"config": map[string]interface{}{"minute": 60, "policy": "local"},
+			}},
+		}},
+		"consumers": []interface{}{map[string]interface{}{"username": "mobile-app"}},
+		"plugins": []interface{}{map[string]interface{}{
+			"name":   "correlation-id",
+			"config": map[string]interface{}{"header_name": "X-Request-ID"},
+		}},
+	}
+	if !reflect.DeepEqual(expected, document) {
+		t.Errorf("Expected declarative config %v, got:\n%s", expected, content)
+	}
+
+	var roundTripped declarativeConfig
+	if err = yaml.Unmarshal([]byte(content), &roundTripped); err != nil {
+		t.Fatal(err)
+	}
+	if errs := roundTripped.validate(""); len(errs) > 0 {
+		t.Errorf("Expected generated config to pass validation, got: %v", errs)
+	}
+}
+
+func TestValidateDeclarativeConfig(t *testing.T) {
+	data := []struct {
+		name      string
+		modify    func(config *Config)
+		expectErr bool
+	}{
+		{"nothing", func(config *Config) { *config = Config{} }, false},
+		{"services, consumers and plugins", func(config *Config) {}, false},
+		{"upstream by host", func(config *Config) {
+			config.Services[0].Url = ""
+			config.Services[0].Host = "orders.internal"
+			config.Services[0].Port = 8080
+		}, false},
+		{"missing upstream", func(config *Config) { config.Services[0].Url = "" }, true},
+		{"url and host", func(config *Config) { config.Services[0].Host = "orders.internal" }, true},
+		{"relative url", func(config *Config) { config.Services[0].Url = "/api" }, true},
+		{"unsupported protocol", func(config *Config) { config.Services[0].Url = "ftp://10.0.0.5/api" }, true},
+		{"duplicate service", func(config *Config) { config.Services = append(config.Services, config.Services[0]) }, true},
+		{"route without matcher", func(config *Config) { config.Services[0].Routes[0] = Route{Name: "orders"} }, true},
+		{"relative path", func(config *Config) { config.Services[0].Routes[0].Paths = []string{"orders"} }, true},
+		{"lower-case method", func(config *Config) { config.Services[0].Routes[0].Methods = []string{"get"} }, true},
+		{"plugin config not an object", func(config *Config) { config.Plugins[0].Config = `["X-Request-ID"]` }, true},
+		{"plugin enabled twice", func(config *Config) { config.Plugins = append(config.Plugins, config.Plugins[0]) }, true},
+		{"anonymous consumer", func(config *Config) { config.Consumers[0] = Consumer{} }, true},
+		{"duplicate consumer", func(config *Config) { config.Consumers = append(config.Consumers, config.Consumers[0]) }, true},
+		{"file and services", func(config *Config) { config.DeclarativeConfigFile = "kong.yaml" }, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			config := testDeclarativeConfig()
+			d.modify(&config)
+
+			errs := config.validateDeclarativeConfig()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateDeclarativeConfigFile(t *testing.T) {
+	data := []struct {
+		name      string
+		content   string
+		expectErr bool
+	}{
+		{
+			"decK file",
+			`_format_version: "3.0"
+services:
+  - name: orders
+    host: orders.internal
+    port: 8080
+    routes:
+      - name: orders
+        paths: [/orders]
+upstreams:
+  - name: orders.internal
+    targets:
+      - target: 10.0.0.5:8080
+`,
+			false,
+		},
+		{"missing format version", "services: []\n", true},
+		{"not YAML", "_format_version: [3.0\n", true},
+		{
+			"scoped plugins and stream routes",
+			`_format_version: "3.0"
+services:
+  - name: orders
+    url: tls://10.0.0.5:8443
+    routes:
+      - name: orders-tls
+        protocols: [tls]
+        snis: [orders.mycompany.com]
+plugins:
+  - name: rate-limiting
+    service: orders
+    config:
+      minute: 60
+  - name: rate-limiting
+    route: orders-tls
+    config:
+      minute: 10
+`,
+			false,
+		},
+		{"not a mapping", "- _format_version: \"3.0\"\n", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "kong.yaml")
+			if err := os.WriteFile(file, []byte(d.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := (&Config{DeclarativeConfigFile: file}).validateDeclarativeConfig()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+
+	if errs := (&Config{DeclarativeConfigFile: "/does/not/exist/kong.yaml"}).validateDeclarativeConfig(); len(errs) == 0 {
+		t.Error("Expected missing file to be rejected")
+	}
+}
+
+func TestProvisionDeclarativeConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "kong.yaml")
+	content := "_format_version: \"3.0\"\nservices:\n  - name: orders\n    url: http://10.0.0.5:8080\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"kongApiGatewayDomain":  "api.mycompany.com",
+		"sslCertMode":           "self-signed",
+		"declarativeConfigFile": file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded := communicator.Uploaded("/home/ubuntu/kong.yaml")
+	if uploaded == nil || string(uploaded.Content) != content {
+		t.Errorf("Expected declarative config file to be uploaded as is, got: %v", uploaded)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 5 {
+		t.Fatalf("Expected 5 steps to run, got %d", len(scripts))
+	}
+	if !strings.Contains(scripts[2], "mv /home/ubuntu/kong.yaml /home/ubuntu/docker-kong/compose/config/kong.yaml\n") {
+		t.Errorf("Expected declarative config to be moved into docker-kong after cloning it, got:\n%s", scripts[2])
+	}
+}
@@ -1,7 +1,7 @@
 // Copyright (c) Jiaqi Liu
 // SPDX-License-Identifier: MPL-2.0
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,Service,Route,Consumer,Plugin
 
 package gateway
 
@@ -12,17 +12,51 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/render"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"path/filepath"
 	"text/template"
 )
 
 type Config struct {
-	SslCertBase64        string `mapstructure:"sslCertBase64" required:"true"`
-	SslCertKeyBase64     string `mapstructure:"sslCertKeyBase64" required:"true"`
 	KongApiGatewayDomain string `mapstructure:"kongApiGatewayDomain" required:"true"`
 	HomeDir              string `mapstructure:"homeDir" required:"false"`
 
+	Offline              bool   `mapstructure:"offline" required:"false"`
+	DockerPackagesSource string `mapstructure:"dockerPackagesSource" required:"false"`
+	DockerKongSource     string `mapstructure:"dockerKongSource" required:"false"`
+
+	DeclarativeConfigFile string     `mapstructure:"declarativeConfigFile" required:"false"`
+	Services              []Service  `mapstructure:"services" required:"false"`
+	Consumers             []Consumer `mapstructure:"consumers" required:"false"`
+	Plugins               []Plugin   `mapstructure:"plugins" required:"false"`
+
+	AdminLocalhostOnly bool     `mapstructure:"adminLocalhostOnly" required:"false"`
+	AdminAllowedCidrs  []string `mapstructure:"adminAllowedCidrs" required:"false"`
+
+	AdminBasicAuthUser string `mapstructure:"adminBasicAuthUser" required:"false"`
+	// AdminBasicAuthPasswordHash The hash of the basic auth password, such as one printed by "openssl passwd -6",
+	// rather than the password itself
+	AdminBasicAuthPasswordHash string `mapstructure:"adminBasicAuthPasswordHash" required:"false"`
+
+	AdminClientCaBase64 string `mapstructure:"adminClientCaBase64" required:"false"`
+	AdminClientCaFile   string `mapstructure:"adminClientCaFile" required:"false"`
+
+	CorsMode             string   `mapstructure:"corsMode" required:"false"`
+	CorsAllowedOrigins   []string `mapstructure:"corsAllowedOrigins" required:"false"`
+	CorsAllowedMethods   []string `mapstructure:"corsAllowedMethods" required:"false"`
+	CorsAllowedHeaders   []string `mapstructure:"corsAllowedHeaders" required:"false"`
+	CorsExposedHeaders   []string `mapstructure:"corsExposedHeaders" required:"false"`
+	CorsAllowCredentials bool     `mapstructure:"corsAllowCredentials" required:"false"`
+	CorsMaxAgeSeconds    int      `mapstructure:"corsMaxAgeSeconds" required:"false"`
+
+	Ssl    ssl.Config    `mapstructure:",squash"`
+	DryRun render.Config `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -40,31 +74,156 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		return err
 	}
 
-	return nil
+	errs := validation.CheckRequired(&p.config)
+	errs = append(errs, validation.CheckDomain("kongApiGatewayDomain", p.config.KongApiGatewayDomain))
+	errs = append(errs, p.config.Ssl.Validate(p.config.KongApiGatewayDomain)...)
+	errs = append(
+		errs,
+		validation.CheckOfflineSource(p.config.Offline, "dockerPackagesSource", p.config.DockerPackagesSource),
+		validation.CheckOfflineSource(p.config.Offline, "dockerKongSource", p.config.DockerKongSource),
+		p.config.Ssl.CheckOffline(p.config.Offline),
+	)
+	errs = append(errs, p.config.validateDeclarativeConfig()...)
+	errs = append(errs, p.config.validateAdminAccess()...)
+	errs = append(errs, p.config.validateCors()...)
+	errs = append(errs, p.config.DryRun.Validate()...)
+
+	return validation.Combine(errs...)
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, generatedData map[string]interface{}) error {
+	return p.config.DryRun.Run(ctx, ui, communicator, p.provision)
+}
+
+func (p *Provisioner) provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
 	p.config.HomeDir = ssl.GetHomeDir(p.config.HomeDir)
-	err := shell.Provision(ctx, ui, communicator, getCommands())
+
+	d, err := distro.Detect(ctx, communicator)
+	if err != nil {
+		return err
+	}
+
+	dockerStep, err := shell.StepInstallingDocker(p.config.ctx, ui, communicator, d, p.config.HomeDir, p.config.DockerPackagesSource)
+	if err != nil {
+		return err
+	}
+
+	if p.config.DockerKongSource != "" {
+		dockerKongDst := filepath.Join(p.config.HomeDir, "docker-kong")
+		if err = file.ProvisionDirContents(p.config.ctx, ui, communicator, p.config.DockerKongSource, dockerKongDst); err != nil {
+			return err
+		}
+	}
+
+	steps := getSteps(d, dockerStep, p.config.DockerKongSource != "")
+	if p.config.hasDeclarativeConfig() {
+		declarativeConfig, err := p.config.getDeclarativeConfig()
+		if err != nil {
+			return err
+		}
+
+		declarativeConfigDst := filepath.Join(p.config.HomeDir, stagedDeclarativeConfigFilename)
+		if err = ssl.UploadContent(p.config.ctx, ui, communicator, declarativeConfig, declarativeConfigDst); err != nil {
+			return err
+		}
+
+		steps = append(steps, getStepInstallingDeclarativeConfig(p.config.HomeDir))
+	}
+
+	if p.config.hasAdminClientCa() {
+		clientCa, err := p.config.loadAdminClientCa()
+		if err != nil {
+			return err
+		}
+
+		if err = ssl.UploadContent(p.config.ctx, ui, communicator, clientCa, filepath.Join(p.config.HomeDir, adminClientCaFilename)); err != nil {
+			return err
+		}
+
+		steps = append(steps, getStepInstallingAdminClientCa(p.config.HomeDir))
+	}
+
+	err = shell.Provision(ctx, ui, communicator, steps)
 	if err != nil {
 		return err
 	}
 
-	return ssl.Provision(ctx, p.config.ctx, ui, communicator, p.config.HomeDir, p.config.SslCertBase64, p.config.SslCertKeyBase64, getNginxConfig(p.config.KongApiGatewayDomain))
+	if !p.config.adminAccessControlled() {
+		ui.Error("Warning: Kong Admin API and Manager are exposed on ports 8444 and 8445 to anyone; consider restricting them with adminLocalhostOnly, adminAllowedCidrs, adminBasicAuthUser or adminClientCaFile")
+	}
+
+	err = ssl.Provision(ctx, p.config.ctx, ui, communicator, d, p.config.HomeDir, p.config.Ssl, p.config.KongApiGatewayDomain, getNginxConfig(p.config))
+	if err != nil || p.config.AdminBasicAuthUser == "" {
+		return err
+	}
+
+	htpasswdDst := filepath.Join(p.config.HomeDir, adminHtpasswdFilename)
+	if err = ssl.UploadContent(p.config.ctx, ui, communicator, p.config.getAdminHtpasswd(), htpasswdDst); err != nil {
+		return err
+	}
+
+	return shell.Provision(ctx, ui, communicator, []shell.Step{getStepInstallingAdminHtpasswd(p.config.HomeDir)})
 }
 
-func getCommands() []string {
-	return append(shell.CommandsInstallingSudoLessDocker(), []string{"git clone https://github.com/QubitPi/docker-kong.git"}...)
+// Returns the steps that install Docker and, unless docker-kong has been uploaded already, clone docker-kong
+func getSteps(d *distro.Distro, dockerStep shell.Step, dockerKongUploaded bool) []shell.Step {
+	if dockerKongUploaded {
+		return []shell.Step{dockerStep}
+	}
+
+	return []shell.Step{
+		dockerStep,
+		{
+			Name:     "Cloning docker-kong",
+			Commands: []string{d.InstallCommand("git"), "git clone https://github.com/QubitPi/docker-kong.git"},
+			Creates:  "docker-kong",
+		},
+	}
 }
 
-func getNginxConfig(domain string) string {
+// Returns the Nginx config that terminates SSL for the domain and proxies to the Kong proxy on port 443 and to the Admin
+// API and Manager on ports 8444 and 8445. Unless corsMode says otherwise, Nginx answers CORS preflight requests to the
+// Kong proxy and adds the CORS headers to its responses, including errors such as 401 and 429.
+//
+// The Admin API and Manager are reachable from the machine itself only if adminLocalhostOnly is set, and otherwise from
+// the allowed CIDR blocks, with basic auth and with a client certificate signed by the client CA, as far as configured
+func getNginxConfig(config Config) string {
 	var sslConfigs = struct {
-		Domain        string
-		SslCertDst    string
-		SslCertKeyDst string
-	}{domain, ssl.SslCertDst, ssl.SslCertKeyDst}
+		Domain         string
+		SslCertDst     string
+		SslCertKeyDst  string
+		AdminEndpoints []adminEndpoint
+		LocalhostOnly  bool
+		AllowedCidrs   []string
+		HtpasswdDst    string
+		ClientCaDst    string
+		Cors           *nginxCors
+	}{
+		Domain:         config.KongApiGatewayDomain,
+		SslCertDst:     ssl.SslCertDst,
+		SslCertKeyDst:  ssl.SslCertKeyDst,
+		AdminEndpoints: adminEndpoints,
+		LocalhostOnly:  config.AdminLocalhostOnly,
+		AllowedCidrs:   config.AdminAllowedCidrs,
+		Cors:           config.getNginxCors(),
+	}
+	if config.AdminBasicAuthUser != "" {
+		sslConfigs.HtpasswdDst = AdminHtpasswdDst
+	}
+	if config.hasAdminClientCa() {
+		sslConfigs.ClientCaDst = AdminClientCaDst
+	}
+
 	var buf bytes.Buffer
 	t := template.Must(template.New("Nginx Config").Parse(`
+{{- with .Cors}}{{if .Origins}}
+map $http_origin $kong_cors_origin {
+    default "";
+{{- range .Origins}}
+    {{.}} $http_origin;
+{{- end}}
+}
+{{end}}{{end}}
 server {
     listen 80 default_server;
     listen [::]:80 default_server;
@@ -87,18 +246,35 @@ server {
     server_name {{.Domain}};
 
     location / {
+{{- with .Cors}}
         if ($request_method = 'OPTIONS') {
-            add_header 'Access-Control-Allow-Origin'  '*';
-            add_header 'Access-Control-Allow-Methods' 'GET, POST, OPTIONS, HEAD';
-            add_header 'Access-Control-Allow-Headers' 'Authorization, Origin, X-Requested-With, Content-Type, Accept';
+            add_header 'Access-Control-Allow-Origin' {{.AllowOrigin}};
+            add_header 'Access-Control-Allow-Methods' '{{.AllowMethods}}';
+            add_header 'Access-Control-Allow-Headers' '{{.AllowHeaders}}';
+{{- if .AllowCredentials}}
+            add_header 'Access-Control-Allow-Credentials' 'true';
+{{- end}}
+{{- if .MaxAgeSeconds}}
+            add_header 'Access-Control-Max-Age' '{{.MaxAgeSeconds}}';
+{{- end}}
+{{- if .Origins}}
+            add_header 'Vary' 'Origin';
+{{- end}}
 
             return 200;
         }
 
-        if ($request_method ~* '(GET|POST)') {
-            add_header 'Access-Control-Allow-Origin' '*';
-        }
-
+        add_header 'Access-Control-Allow-Origin' {{.AllowOrigin}} always;
+{{- if .AllowCredentials}}
+        add_header 'Access-Control-Allow-Credentials' 'true' always;
+{{- end}}
+{{- if .ExposeHeaders}}
+        add_header 'Access-Control-Expose-Headers' '{{.ExposeHeaders}}' always;
+{{- end}}
+{{- if .Origins}}
+        add_header 'Vary' 'Origin' always;
+{{- end}}
+{{end}}
         proxy_pass http://localhost:8000;
     }
 
@@ -117,37 +293,42 @@ server {
     server_name {{.Domain}};
     return 404;
 }
-
+{{range .AdminEndpoints}}
 server {
     root /var/www/html;
 
     index index.html index.htm index.nginx-debian.html;
-    server_name {{.Domain}};
+    server_name {{$.Domain}};
+{{- if $.AllowedCidrs}}
+{{range $.AllowedCidrs}}
+    allow {{.}};
+{{- end}}
+    deny all;
+{{- end}}
+{{- if $.HtpasswdDst}}
+
+    auth_basic "Kong Admin";
+    auth_basic_user_file {{$.HtpasswdDst}};
+{{- end}}
 
     location / {
-        proxy_pass http://localhost:8001;
+        proxy_pass http://localhost:{{.KongPort}};
     }
-
-    listen [::]:8444 ssl ipv6only=on;
-    listen 8444 ssl;
-    ssl_certificate {{.SslCertDst}};
-    ssl_certificate_key {{.SslCertKeyDst}};
-}
-server {
-    root /var/www/html;
-
-    index index.html index.htm index.nginx-debian.html;
-    server_name {{.Domain}};
-
-    location / {
-        proxy_pass http://localhost:8002;
-    }
-
-    listen [::]:8445 ssl ipv6only=on;
-    listen 8445 ssl;
-    ssl_certificate {{.SslCertDst}};
-    ssl_certificate_key {{.SslCertKeyDst}};
+{{if $.LocalhostOnly}}
+    listen [::1]:{{.ListenPort}} ssl;
+    listen 127.0.0.1:{{.ListenPort}} ssl;
+{{- else}}
+    listen [::]:{{.ListenPort}} ssl ipv6only=on;
+    listen {{.ListenPort}} ssl;
+{{- end}}
+    ssl_certificate {{$.SslCertDst}};
+    ssl_certificate_key {{$.SslCertKeyDst}};
+{{- if $.ClientCaDst}}
+    ssl_client_certificate {{$.ClientCaDst}};
+    ssl_verify_client on;
+{{- end}}
 }
+{{- end}}
 	`))
 
 	if err := t.Execute(&buf, sslConfigs); err != nil {
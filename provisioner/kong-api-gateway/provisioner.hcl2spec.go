@@ -10,10 +10,47 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	SslCertBase64        *string `mapstructure:"sslCertBase64" required:"true" cty:"sslCertBase64" hcl:"sslCertBase64"`
-	SslCertKeyBase64     *string `mapstructure:"sslCertKeyBase64" required:"true" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
-	KongApiGatewayDomain *string `mapstructure:"kongApiGatewayDomain" required:"true" cty:"kongApiGatewayDomain" hcl:"kongApiGatewayDomain"`
-	HomeDir              *string `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	KongApiGatewayDomain          *string        `mapstructure:"kongApiGatewayDomain" required:"true" cty:"kongApiGatewayDomain" hcl:"kongApiGatewayDomain"`
+	HomeDir                       *string        `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	Offline                       *bool          `mapstructure:"offline" required:"false" cty:"offline" hcl:"offline"`
+	DockerPackagesSource          *string        `mapstructure:"dockerPackagesSource" required:"false" cty:"dockerPackagesSource" hcl:"dockerPackagesSource"`
+	DockerKongSource              *string        `mapstructure:"dockerKongSource" required:"false" cty:"dockerKongSource" hcl:"dockerKongSource"`
+	DeclarativeConfigFile         *string        `mapstructure:"declarativeConfigFile" required:"false" cty:"declarativeConfigFile" hcl:"declarativeConfigFile"`
+	Services                      []FlatService  `mapstructure:"services" required:"false" cty:"services" hcl:"services"`
+	Consumers                     []FlatConsumer `mapstructure:"consumers" required:"false" cty:"consumers" hcl:"consumers"`
+	Plugins                       []FlatPlugin   `mapstructure:"plugins" required:"false" cty:"plugins" hcl:"plugins"`
+	AdminLocalhostOnly            *bool          `mapstructure:"adminLocalhostOnly" required:"false" cty:"adminLocalhostOnly" hcl:"adminLocalhostOnly"`
+	AdminAllowedCidrs             []string       `mapstructure:"adminAllowedCidrs" required:"false" cty:"adminAllowedCidrs" hcl:"adminAllowedCidrs"`
+	AdminBasicAuthUser            *string        `mapstructure:"adminBasicAuthUser" required:"false" cty:"adminBasicAuthUser" hcl:"adminBasicAuthUser"`
+	AdminBasicAuthPasswordHash    *string        `mapstructure:"adminBasicAuthPasswordHash" required:"false" cty:"adminBasicAuthPasswordHash" hcl:"adminBasicAuthPasswordHash"`
+	AdminClientCaBase64           *string        `mapstructure:"adminClientCaBase64" required:"false" cty:"adminClientCaBase64" hcl:"adminClientCaBase64"`
+	AdminClientCaFile             *string        `mapstructure:"adminClientCaFile" required:"false" cty:"adminClientCaFile" hcl:"adminClientCaFile"`
+	CorsMode                      *string        `mapstructure:"corsMode" required:"false" cty:"corsMode" hcl:"corsMode"`
+	CorsAllowedOrigins            []string       `mapstructure:"corsAllowedOrigins" required:"false" cty:"corsAllowedOrigins" hcl:"corsAllowedOrigins"`
+	CorsAllowedMethods            []string       `mapstructure:"corsAllowedMethods" required:"false" cty:"corsAllowedMethods" hcl:"corsAllowedMethods"`
+	CorsAllowedHeaders            []string       `mapstructure:"corsAllowedHeaders" required:"false" cty:"corsAllowedHeaders" hcl:"corsAllowedHeaders"`
+	CorsExposedHeaders            []string       `mapstructure:"corsExposedHeaders" required:"false" cty:"corsExposedHeaders" hcl:"corsExposedHeaders"`
+	CorsAllowCredentials          *bool          `mapstructure:"corsAllowCredentials" required:"false" cty:"corsAllowCredentials" hcl:"corsAllowCredentials"`
+	CorsMaxAgeSeconds             *int           `mapstructure:"corsMaxAgeSeconds" required:"false" cty:"corsMaxAgeSeconds" hcl:"corsMaxAgeSeconds"`
+	SslCertMode                   *string        `mapstructure:"sslCertMode" required:"false" cty:"sslCertMode" hcl:"sslCertMode"`
+	SslCertBase64                 *string        `mapstructure:"sslCertBase64" required:"false" cty:"sslCertBase64" hcl:"sslCertBase64"`
+	SslCertKeyBase64              *string        `mapstructure:"sslCertKeyBase64" required:"false" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
+	SslCertFile                   *string        `mapstructure:"sslCertFile" required:"false" cty:"sslCertFile" hcl:"sslCertFile"`
+	SslCertKeyFile                *string        `mapstructure:"sslCertKeyFile" required:"false" cty:"sslCertKeyFile" hcl:"sslCertKeyFile"`
+	SslCertEnv                    *string        `mapstructure:"sslCertEnv" required:"false" cty:"sslCertEnv" hcl:"sslCertEnv"`
+	SslCertKeyEnv                 *string        `mapstructure:"sslCertKeyEnv" required:"false" cty:"sslCertKeyEnv" hcl:"sslCertKeyEnv"`
+	SslChainFile                  *string        `mapstructure:"sslChainFile" required:"false" cty:"sslChainFile" hcl:"sslChainFile"`
+	SslCertExpiryWindowDays       *int           `mapstructure:"sslCertExpiryWindowDays" required:"false" cty:"sslCertExpiryWindowDays" hcl:"sslCertExpiryWindowDays"`
+	SslCertFailWithinExpiryWindow *bool          `mapstructure:"sslCertFailWithinExpiryWindow" required:"false" cty:"sslCertFailWithinExpiryWindow" hcl:"sslCertFailWithinExpiryWindow"`
+	SslCaCertBase64               *string        `mapstructure:"sslCaCertBase64" required:"false" cty:"sslCaCertBase64" hcl:"sslCaCertBase64"`
+	SslCaKeyBase64                *string        `mapstructure:"sslCaKeyBase64" required:"false" cty:"sslCaKeyBase64" hcl:"sslCaKeyBase64"`
+	SslSelfSignedValidityDays     *int           `mapstructure:"sslSelfSignedValidityDays" required:"false" cty:"sslSelfSignedValidityDays" hcl:"sslSelfSignedValidityDays"`
+	SslAcmeEmail                  *string        `mapstructure:"sslAcmeEmail" required:"false" cty:"sslAcmeEmail" hcl:"sslAcmeEmail"`
+	SslAcmeDirectoryUrl           *string        `mapstructure:"sslAcmeDirectoryUrl" required:"false" cty:"sslAcmeDirectoryUrl" hcl:"sslAcmeDirectoryUrl"`
+	SslAcmeDirectoryCaBase64      *string        `mapstructure:"sslAcmeDirectoryCaBase64" required:"false" cty:"sslAcmeDirectoryCaBase64" hcl:"sslAcmeDirectoryCaBase64"`
+	NginxPackagesSource           *string        `mapstructure:"nginxPackagesSource" required:"false" cty:"nginxPackagesSource" hcl:"nginxPackagesSource"`
+	DryRunDir                     *string        `mapstructure:"dryRunDir" required:"false" cty:"dryRunDir" hcl:"dryRunDir"`
+	DryRunDistro                  *string        `mapstructure:"dryRunDistro" required:"false" cty:"dryRunDistro" hcl:"dryRunDistro"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -28,10 +65,171 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"sslCertBase64":        &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
-		"sslCertKeyBase64":     &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
-		"kongApiGatewayDomain": &hcldec.AttrSpec{Name: "kongApiGatewayDomain", Type: cty.String, Required: false},
-		"homeDir":              &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"kongApiGatewayDomain":          &hcldec.AttrSpec{Name: "kongApiGatewayDomain", Type: cty.String, Required: false},
+		"homeDir":                       &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"offline":                       &hcldec.AttrSpec{Name: "offline", Type: cty.Bool, Required: false},
+		"dockerPackagesSource":          &hcldec.AttrSpec{Name: "dockerPackagesSource", Type: cty.String, Required: false},
+		"dockerKongSource":              &hcldec.AttrSpec{Name: "dockerKongSource", Type: cty.String, Required: false},
+		"declarativeConfigFile":         &hcldec.AttrSpec{Name: "declarativeConfigFile", Type: cty.String, Required: false},
+		"services":                      &hcldec.BlockListSpec{TypeName: "services", Nested: hcldec.ObjectSpec((*FlatService)(nil).HCL2Spec())},
+		"consumers":                     &hcldec.BlockListSpec{TypeName: "consumers", Nested: hcldec.ObjectSpec((*FlatConsumer)(nil).HCL2Spec())},
+		"plugins":                       &hcldec.BlockListSpec{TypeName: "plugins", Nested: hcldec.ObjectSpec((*FlatPlugin)(nil).HCL2Spec())},
+		"adminLocalhostOnly":            &hcldec.AttrSpec{Name: "adminLocalhostOnly", Type: cty.Bool, Required: false},
+		"adminAllowedCidrs":             &hcldec.AttrSpec{Name: "adminAllowedCidrs", Type: cty.List(cty.String), Required: false},
+		"adminBasicAuthUser":            &hcldec.AttrSpec{Name: "adminBasicAuthUser", Type: cty.String, Required: false},
+		"adminBasicAuthPasswordHash":    &hcldec.AttrSpec{Name: "adminBasicAuthPasswordHash", Type: cty.String, Required: false},
+		"adminClientCaBase64":           &hcldec.AttrSpec{Name: "adminClientCaBase64", Type: cty.String, Required: false},
+		"adminClientCaFile":             &hcldec.AttrSpec{Name: "adminClientCaFile", Type: cty.String, Required: false},
+		"corsMode":                      &hcldec.AttrSpec{Name: "corsMode", Type: cty.String, Required: false},
+		"corsAllowedOrigins":            &hcldec.AttrSpec{Name: "corsAllowedOrigins", Type: cty.List(cty.String), Required: false},
+		"corsAllowedMethods":            &hcldec.AttrSpec{Name: "corsAllowedMethods", Type: cty.List(cty.String), Required: false},
+		"corsAllowedHeaders":            &hcldec.AttrSpec{Name: "corsAllowedHeaders", Type: cty.List(cty.String), Required: false},
+		"corsExposedHeaders":            &hcldec.AttrSpec{Name: "corsExposedHeaders", Type: cty.List(cty.String), Required: false},
+		"corsAllowCredentials":          &hcldec.AttrSpec{Name: "corsAllowCredentials", Type: cty.Bool, Required: false},
+		"corsMaxAgeSeconds":             &hcldec.AttrSpec{Name: "corsMaxAgeSeconds", Type: cty.Number, Required: false},
+		"sslCertMode":                   &hcldec.AttrSpec{Name: "sslCertMode", Type: cty.String, Required: false},
+		"sslCertBase64":                 &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
+		"sslCertKeyBase64":              &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
+		"sslCertFile":                   &hcldec.AttrSpec{Name: "sslCertFile", Type: cty.String, Required: false},
+		"sslCertKeyFile":                &hcldec.AttrSpec{Name: "sslCertKeyFile", Type: cty.String, Required: false},
+		"sslCertEnv":                    &hcldec.AttrSpec{Name: "sslCertEnv", Type: cty.String, Required: false},
+		"sslCertKeyEnv":                 &hcldec.AttrSpec{Name: "sslCertKeyEnv", Type: cty.String, Required: false},
+		"sslChainFile":                  &hcldec.AttrSpec{Name: "sslChainFile", Type: cty.String, Required: false},
+		"sslCertExpiryWindowDays":       &hcldec.AttrSpec{Name: "sslCertExpiryWindowDays", Type: cty.Number, Required: false},
+		"sslCertFailWithinExpiryWindow": &hcldec.AttrSpec{Name: "sslCertFailWithinExpiryWindow", Type: cty.Bool, Required: false},
+		"sslCaCertBase64":               &hcldec.AttrSpec{Name: "sslCaCertBase64", Type: cty.String, Required: false},
+		"sslCaKeyBase64":                &hcldec.AttrSpec{Name: "sslCaKeyBase64", Type: cty.String, Required: false},
+		"sslSelfSignedValidityDays":     &hcldec.AttrSpec{Name: "sslSelfSignedValidityDays", Type: cty.Number, Required: false},
+		"sslAcmeEmail":                  &hcldec.AttrSpec{Name: "sslAcmeEmail", Type: cty.String, Required: false},
+		"sslAcmeDirectoryUrl":           &hcldec.AttrSpec{Name: "sslAcmeDirectoryUrl", Type: cty.String, Required: false},
+		"sslAcmeDirectoryCaBase64":      &hcldec.AttrSpec{Name: "sslAcmeDirectoryCaBase64", Type: cty.String, Required: false},
+		"nginxPackagesSource":           &hcldec.AttrSpec{Name: "nginxPackagesSource", Type: cty.String, Required: false},
+		"dryRunDir":                     &hcldec.AttrSpec{Name: "dryRunDir", Type: cty.String, Required: false},
+		"dryRunDistro":                  &hcldec.AttrSpec{Name: "dryRunDistro", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatConsumer is an auto-generated flat version of Consumer.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConsumer struct {
+	Username *string      `mapstructure:"username" required:"false" yaml:"username,omitempty" cty:"username" hcl:"username"`
+	CustomId *string      `mapstructure:"customId" required:"false" yaml:"custom_id,omitempty" cty:"customId" hcl:"customId"`
+	Plugins  []FlatPlugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty" cty:"plugins" hcl:"plugins"`
+}
+
+// FlatMapstructure returns a new FlatConsumer.
+// FlatConsumer is an auto-generated flat version of Consumer.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Consumer) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConsumer)
+}
+
+// HCL2Spec returns the hcl spec of a Consumer.
+// This spec is used by HCL to read the fields of Consumer.
+// The decoded values from this spec will then be applied to a FlatConsumer.
+func (*FlatConsumer) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"username": &hcldec.AttrSpec{Name: "username", Type: cty.String, Required: false},
+		"customId": &hcldec.AttrSpec{Name: "customId", Type: cty.String, Required: false},
+		"plugins":  &hcldec.BlockListSpec{TypeName: "plugins", Nested: hcldec.ObjectSpec((*FlatPlugin)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatPlugin is an auto-generated flat version of Plugin.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatPlugin struct {
+	Name   *string `mapstructure:"name" required:"true" cty:"name" hcl:"name"`
+	Config *string `mapstructure:"config" required:"false" cty:"config" hcl:"config"`
+}
+
+// FlatMapstructure returns a new FlatPlugin.
+// FlatPlugin is an auto-generated flat version of Plugin.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Plugin) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatPlugin)
+}
+
+// HCL2Spec returns the hcl spec of a Plugin.
+// This spec is used by HCL to read the fields of Plugin.
+// The decoded values from this spec will then be applied to a FlatPlugin.
+func (*FlatPlugin) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":   &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"config": &hcldec.AttrSpec{Name: "config", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatRoute is an auto-generated flat version of Route.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRoute struct {
+	Name         *string      `mapstructure:"name" required:"false" yaml:"name,omitempty" cty:"name" hcl:"name"`
+	Paths        []string     `mapstructure:"paths" required:"false" yaml:"paths,omitempty" cty:"paths" hcl:"paths"`
+	Hosts        []string     `mapstructure:"hosts" required:"false" yaml:"hosts,omitempty" cty:"hosts" hcl:"hosts"`
+	Methods      []string     `mapstructure:"methods" required:"false" yaml:"methods,omitempty" cty:"methods" hcl:"methods"`
+	StripPath    *bool        `mapstructure:"stripPath" required:"false" yaml:"strip_path,omitempty" cty:"stripPath" hcl:"stripPath"`
+	PreserveHost *bool        `mapstructure:"preserveHost" required:"false" yaml:"preserve_host,omitempty" cty:"preserveHost" hcl:"preserveHost"`
+	Plugins      []FlatPlugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty" cty:"plugins" hcl:"plugins"`
+}
+
+// FlatMapstructure returns a new FlatRoute.
+// FlatRoute is an auto-generated flat version of Route.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Route) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRoute)
+}
+
+// HCL2Spec returns the hcl spec of a Route.
+// This spec is used by HCL to read the fields of Route.
+// The decoded values from this spec will then be applied to a FlatRoute.
+func (*FlatRoute) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":         &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"paths":        &hcldec.AttrSpec{Name: "paths", Type: cty.List(cty.String), Required: false},
+		"hosts":        &hcldec.AttrSpec{Name: "hosts", Type: cty.List(cty.String), Required: false},
+		"methods":      &hcldec.AttrSpec{Name: "methods", Type: cty.List(cty.String), Required: false},
+		"stripPath":    &hcldec.AttrSpec{Name: "stripPath", Type: cty.Bool, Required: false},
+		"preserveHost": &hcldec.AttrSpec{Name: "preserveHost", Type: cty.Bool, Required: false},
+		"plugins":      &hcldec.BlockListSpec{TypeName: "plugins", Nested: hcldec.ObjectSpec((*FlatPlugin)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatService is an auto-generated flat version of Service.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatService struct {
+	Name     *string      `mapstructure:"name" required:"true" yaml:"name" cty:"name" hcl:"name"`
+	Url      *string      `mapstructure:"url" required:"false" yaml:"url,omitempty" cty:"url" hcl:"url"`
+	Host     *string      `mapstructure:"host" required:"false" yaml:"host,omitempty" cty:"host" hcl:"host"`
+	Port     *int         `mapstructure:"port" required:"false" yaml:"port,omitempty" cty:"port" hcl:"port"`
+	Protocol *string      `mapstructure:"protocol" required:"false" yaml:"protocol,omitempty" cty:"protocol" hcl:"protocol"`
+	Path     *string      `mapstructure:"path" required:"false" yaml:"path,omitempty" cty:"path" hcl:"path"`
+	Routes   []FlatRoute  `mapstructure:"routes" required:"false" yaml:"routes,omitempty" cty:"routes" hcl:"routes"`
+	Plugins  []FlatPlugin `mapstructure:"plugins" required:"false" yaml:"plugins,omitempty" cty:"plugins" hcl:"plugins"`
+}
+
+// FlatMapstructure returns a new FlatService.
+// FlatService is an auto-generated flat version of Service.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Service) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatService)
+}
+
+// HCL2Spec returns the hcl spec of a Service.
+// This spec is used by HCL to read the fields of Service.
+// The decoded values from this spec will then be applied to a FlatService.
+func (*FlatService) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":     &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"url":      &hcldec.AttrSpec{Name: "url", Type: cty.String, Required: false},
+		"host":     &hcldec.AttrSpec{Name: "host", Type: cty.String, Required: false},
+		"port":     &hcldec.AttrSpec{Name: "port", Type: cty.Number, Required: false},
+		"protocol": &hcldec.AttrSpec{Name: "protocol", Type: cty.String, Required: false},
+		"path":     &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+		"routes":   &hcldec.BlockListSpec{TypeName: "routes", Nested: hcldec.ObjectSpec((*FlatRoute)(nil).HCL2Spec())},
+		"plugins":  &hcldec.BlockListSpec{TypeName: "plugins", Nested: hcldec.ObjectSpec((*FlatPlugin)(nil).HCL2Spec())},
 	}
 	return s
 }
@@ -0,0 +1,83 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package gateway
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"strings"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"kongApiGatewayDomain": "api.mycompany.com",
+		"sslCertMode":          "self-signed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 4 {
+		t.Fatalf("Expected 4 steps to run, got %d", len(scripts))
+	}
+	if !strings.Contains(scripts[1], "git clone https://github.com/QubitPi/docker-kong.git\n") {
+		t.Errorf("Expected docker-kong to be cloned, got:\n%s", scripts[1])
+	}
+
+	nginxConfig := communicator.Uploaded("/home/ubuntu/nginx-ssl.conf")
+	if nginxConfig == nil || string(nginxConfig.Content) != getNginxConfig(provisioner.config) {
+		t.Errorf("Expected the generated Nginx config to be uploaded, got: %v", nginxConfig)
+	}
+	if communicator.Uploaded("/home/ubuntu/ssl.key") == nil {
+		t.Error("Expected SSL certificate key to be uploaded")
+	}
+}
+
+func TestProvisionOffline(t *testing.T) {
+	dockerPackages := t.TempDir()
+	dockerKong := t.TempDir()
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"kongApiGatewayDomain": "api.mycompany.com",
+		"sslCertMode":          "self-signed",
+		"offline":              true,
+		"dockerPackagesSource": dockerPackages,
+		"dockerKongSource":     dockerKong,
+		"nginxPackagesSource":  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDirUploads := []communicatortest.DirUpload{
+		{Destination: "/home/ubuntu/docker-packages", Source: dockerPackages + "/"},
+		{Destination: "/home/ubuntu/docker-kong", Source: dockerKong + "/"},
+	}
+	for i, expected := range expectedDirUploads {
+		if actual := communicator.DirUploads[i]; actual.Destination != expected.Destination || actual.Source != expected.Source {
+			t.Errorf("Expected upload of '%s' to '%s', got: %+v", expected.Source, expected.Destination, actual)
+		}
+	}
+
+	for _, script := range communicator.Scripts() {
+		if strings.Contains(script, "git clone") {
+			t.Errorf("Expected nothing to be cloned in offline mode, got:\n%s", script)
+		}
+	}
+}
@@ -13,26 +13,58 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/render"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
 	"path/filepath"
 	"text/template"
 )
 
 // PORT Default port of React app
+//
+// Deprecated: the port is configurable by appPort, which defaults to DefaultAppPort
 const PORT string = "3000"
 
 // NODE_VERSION Default node version running the React app
 const NODE_VERSION = "18"
 
+// ServeModeProxy Serve mode in which Node.js is installed, serve runs the React app as a systemd service and Nginx
+// proxies to it on appPort
+const ServeModeProxy string = "proxy"
+
+// ServeModeStatic Serve mode in which Nginx serves the dist from WebRoot itself, without Node.js
+const ServeModeStatic string = "static"
+
+// WebRoot The directory that Nginx serves the dist from in "static" mode
+const WebRoot string = "/var/www/react"
+
 type Config struct {
-	DistSource       string `mapstructure:"distSource" required:"true"`
-	SslCertBase64    string `mapstructure:"sslCertBase64" required:"true"`
-	SslCertKeyBase64 string `mapstructure:"sslCertKeyBase64" required:"true"`
-	AppDomain        string `mapstructure:"appDomain" required:"true"`
-	NodeVersion      string `mapstructure:"nodeVersion" required:"false"`
-	HomeDir          string `mapstructure:"homeDir" required:"false"`
+	DistSource  string `mapstructure:"distSource" required:"false"`
+	AppDomain   string `mapstructure:"appDomain" required:"true"`
+	ServeMode   string `mapstructure:"serveMode" required:"false"`
+	NodeVersion string `mapstructure:"nodeVersion" required:"false"`
+	HomeDir     string `mapstructure:"homeDir" required:"false"`
+	AppPort     int    `mapstructure:"appPort" required:"false"`
+	ServiceUser string `mapstructure:"serviceUser" required:"false"`
+
+	SourceDir      string   `mapstructure:"sourceDir" required:"false"`
+	SourceExcludes []string `mapstructure:"sourceExcludes" required:"false"`
+	PackageManager string   `mapstructure:"packageManager" required:"false"`
+	BuildCommand   string   `mapstructure:"buildCommand" required:"false"`
+	BuildOutputDir string   `mapstructure:"buildOutputDir" required:"false"`
+
+	RuntimeEnv       map[string]string `mapstructure:"runtimeEnv" required:"false"`
+	RuntimeEnvFormat string            `mapstructure:"runtimeEnvFormat" required:"false"`
+	RuntimeEnvOnBoot bool              `mapstructure:"runtimeEnvOnBoot" required:"false"`
+
+	Offline            bool   `mapstructure:"offline" required:"false"`
+	NodePackagesSource string `mapstructure:"nodePackagesSource" required:"false"`
+
+	Ssl    ssl.Config    `mapstructure:",squash"`
+	DryRun render.Config `mapstructure:",squash"`
 
 	ctx interpolate.Context
 }
@@ -51,36 +83,153 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		return err
 	}
 
-	return nil
+	errs := validation.CheckRequired(&p.config)
+	errs = append(
+		errs,
+		validation.CheckSourcePath("distSource", p.config.DistSource),
+		validation.CheckSourcePath("sourceDir", p.config.SourceDir),
+		validation.CheckDomain("appDomain", p.config.AppDomain),
+		validation.CheckOfflineSource(p.config.Offline && p.config.needsNode(), "nodePackagesSource", p.config.NodePackagesSource),
+		p.config.Ssl.CheckOffline(p.config.Offline),
+	)
+	errs = append(errs, p.config.validateServeMode()...)
+	errs = append(errs, p.config.validateSource()...)
+	errs = append(errs, p.config.validateService()...)
+	errs = append(errs, p.config.validateRuntimeEnv()...)
+	errs = append(errs, p.config.Ssl.Validate(p.config.AppDomain)...)
+	errs = append(errs, p.config.DryRun.Validate()...)
+
+	return validation.Combine(errs...)
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, generatedData map[string]interface{}) error {
+	return p.config.DryRun.Run(ctx, ui, communicator, p.provision)
+}
+
+func (p *Provisioner) provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
 	p.config.HomeDir = ssl.GetHomeDir(p.config.HomeDir)
 
+	d, err := distro.Detect(ctx, communicator)
+	if err != nil {
+		return err
+	}
+
 	distFileDst := fmt.Sprintf(filepath.Join(p.config.HomeDir, "dist"))
-	err := file.Provision(p.config.ctx, ui, communicator, p.config.DistSource, distFileDst)
+	if p.config.SourceDir == "" {
+		err = file.Provision(p.config.ctx, ui, communicator, p.config.DistSource, distFileDst)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.config.needsNode() {
+		if err = p.provisionNode(ctx, ui, communicator, d); err != nil {
+			return err
+		}
+	}
+
+	if p.config.SourceDir != "" {
+		if err = p.provisionSource(ctx, ui, communicator); err != nil {
+			return err
+		}
+	}
+
+	servedDir := getServedDistDir()
+	if p.config.isStatic() {
+		servedDir = WebRoot
+		err = shell.Provision(ctx, ui, communicator, []shell.Step{getStepInstallingDist(distFileDst)})
+	} else {
+		err = p.provisionService(ctx, ui, communicator, d)
+	}
 	if err != nil {
 		return err
 	}
 
+	if err = p.provisionRuntimeEnv(ctx, ui, communicator, d, servedDir); err != nil {
+		return err
+	}
+
+	return ssl.Provision(ctx, p.config.ctx, ui, communicator, d, p.config.HomeDir, p.config.Ssl, p.config.AppDomain, getNginxConfig(p.config.AppDomain, p.config.serveMode(), p.config.appPort()))
+}
+
+// Installs Node.js, together with yarn and serve, which build the React app from sourceDir or run it in "proxy" mode
+func (p *Provisioner) provisionNode(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, d *distro.Distro) error {
+	nodePackagesDir := ""
+	if p.config.NodePackagesSource != "" {
+		nodePackagesDir = filepath.Join(p.config.HomeDir, "node-packages")
+		err := file.ProvisionDirContents(p.config.ctx, ui, communicator, p.config.NodePackagesSource, nodePackagesDir)
+		if err != nil {
+			return err
+		}
+	}
+
 	if p.config.NodeVersion == "" {
 		p.config.NodeVersion = NODE_VERSION
 	}
-	err = shell.Provision(ctx, ui, communicator, getCommands(p.config.NodeVersion))
-	if err != nil {
-		return err
+
+	return shell.Provision(ctx, ui, communicator, getSteps(d, p.config.NodeVersion, nodePackagesDir))
+}
+
+func (c *Config) serveMode() string {
+	if c.ServeMode == "" {
+		return ServeModeProxy
 	}
 
-	return ssl.Provision(ctx, p.config.ctx, ui, communicator, p.config.HomeDir, p.config.SslCertBase64, p.config.SslCertKeyBase64, getNginxConfig(p.config.AppDomain))
+	return c.ServeMode
 }
 
-func getNginxConfig(domain string) string {
+func (c *Config) isStatic() bool {
+	return c.serveMode() == ServeModeStatic
+}
+
+// Returns whether Node.js is installed on the image, which it is in order to run the React app in "proxy" mode or to
+// build it from sourceDir
+func (c *Config) needsNode() bool {
+	return !c.isStatic() || c.SourceDir != ""
+}
+
+func (c *Config) validateServeMode() []error {
+	switch c.serveMode() {
+	case ServeModeProxy:
+		return nil
+	case ServeModeStatic:
+		if c.NodePackagesSource != "" && c.SourceDir == "" {
+			return []error{fmt.Errorf("nodePackagesSource: Node.js is not installed in '%s' mode unless building from sourceDir", ServeModeStatic)}
+		}
+		return nil
+	default:
+		return []error{fmt.Errorf("serveMode: unsupported mode '%s', expected '%s' or '%s'", c.ServeMode, ServeModeProxy, ServeModeStatic)}
+	}
+}
+
+// Returns the step that copies the uploaded dist into WebRoot, replacing the dist of any earlier build, and makes it
+// readable by Nginx
+func getStepInstallingDist(distDir string) shell.Step {
+	return shell.Step{
+		Name: "Installing React app into Nginx web root",
+		Commands: []string{
+			fmt.Sprintf("sudo rm -rf %s", WebRoot),
+			fmt.Sprintf("sudo mkdir -p %s", WebRoot),
+			fmt.Sprintf("sudo cp -r %s/. %s/", distDir, WebRoot),
+			fmt.Sprintf("sudo chmod -R a+rX %s", WebRoot),
+		},
+	}
+}
+
+// Returns the Nginx config that serves the React app on the domain over SSL. In "proxy" mode, requests are passed to the
+// React app on the port. In "static" mode, the dist is served from WebRoot, with unknown paths falling back to index.html
+// so that client-side routes load the app. Assets under "static/" and "assets/", where Create React App and Vite put
+// their content-hashed bundles, are cached for a year, whereas index.html is always revalidated so that a new
+// deployment is picked up
+func getNginxConfig(domain string, serveMode string, port int) string {
 	var sslConfigs = struct {
 		Domain        string
 		SslCertDst    string
 		SslCertKeyDst string
-		Port          string
-	}{domain, ssl.SslCertDst, ssl.SslCertKeyDst, PORT}
+		Port          int
+		Static        bool
+		WebRoot       string
+	}{domain, ssl.SslCertDst, ssl.SslCertKeyDst, port, serveMode == ServeModeStatic, WebRoot}
 	var buf bytes.Buffer
 	t := template.Must(template.New("Nginx Config").Parse(`
 server {
@@ -99,14 +248,38 @@ server {
 }
 
 server {
+{{- if .Static}}
+    root {{.WebRoot}};
+
+    index index.html;
+    server_name {{.Domain}};
+
+    location / {
+        try_files $uri $uri/ /index.html;
+    }
+
+    location = /index.html {
+        add_header Cache-Control "no-cache";
+    }
+
+    location ~ ^/env-config\.(js|json)$ {
+        add_header Cache-Control "no-cache";
+    }
+
+    location ~ ^/(static|assets)/ {
+        add_header Cache-Control "public, max-age=31536000, immutable";
+        try_files $uri =404;
+    }
+{{- else}}
     root /var/www/html;
 
     index index.html index.htm index.nginx-debian.html;
     server_name {{.Domain}};
 
     location / {
-        proxy_pass http://localhost:{{.Port}};
+        proxy_pass http://127.0.0.1:{{.Port}};
     }
+{{- end}}
 
     listen [::]:443 ssl ipv6only=on;
     listen 443 ssl;
@@ -132,23 +305,66 @@ server {
 	return buf.String()
 }
 
-func getCommands(nodeVersion string) []string {
-	return append(getCommandsUpdatingUbuntu(), getCommandsInstallingNode(nodeVersion)...)
+// Returns the steps that install Node.js, either over the internet or, if nodePackagesDir is given, from the package
+// files in that directory of remote machine, in which case the system packages are not upgraded either
+func getSteps(d *distro.Distro, nodeVersion string, nodePackagesDir string) []shell.Step {
+	if nodePackagesDir != "" {
+		return []shell.Step{
+			{Name: "Installing Node.js from packages", Commands: getCommandsInstallingNodeFromPackages(d, nodePackagesDir)},
+		}
+	}
+
+	return []shell.Step{
+		{Name: "Updating system packages", Commands: getCommandsUpdatingSystem(d)},
+		{Name: fmt.Sprintf("Installing Node.js %s", nodeVersion), Commands: getCommandsInstallingNode(d, nodeVersion)},
+	}
 }
 
-func getCommandsUpdatingUbuntu() []string {
+func getCommandsUpdatingSystem(d *distro.Distro) []string {
+	if d.PackageManager != distro.Apt {
+		return []string{d.UpgradeCommand()}
+	}
+
 	return []string{
-		"sudo apt update && sudo apt upgrade -y",
-		"sudo apt install software-properties-common -y",
+		d.UpgradeCommand(),
+		d.InstallCommand("software-properties-common"),
 	}
 }
 
-func getCommandsInstallingNode(nodeVersion string) []string {
-	return []string{
-		"sudo apt install -y curl",
-		fmt.Sprintf("curl -fsSL https://deb.nodesource.com/setup_%s.x | sudo -E bash -", nodeVersion),
-		"sudo apt install -y nodejs",
+// Installs Node.js from NodeSource, which hosts one repository per major version for both deb- and rpm-based
+// distributions. Alpine is not covered by NodeSource and gets the Node.js version of its own package repository
+func getCommandsInstallingNode(d *distro.Distro, nodeVersion string) []string {
+	var commands []string
+
+	switch d.PackageManager {
+	case distro.Apt:
+		commands = []string{
+			d.InstallCommand("curl"),
+			fmt.Sprintf("curl -fsSL https://deb.nodesource.com/setup_%s.x | sudo -E bash -", nodeVersion),
+			d.InstallCommand("nodejs"),
+		}
+	case distro.Apk:
+		commands = []string{d.InstallCommand("nodejs", "npm")}
+	default:
+		commands = []string{
+			fmt.Sprintf("curl -fsSL https://rpm.nodesource.com/setup_%s.x | sudo -E bash -", nodeVersion),
+			d.InstallCommand("nodejs"),
+		}
+	}
+
+	return append(
+		commands,
 		"sudo npm install -g yarn",
 		"sudo npm install -g serve",
+	)
+}
+
+// Installs Node.js from the package files of the distribution's format, such as ".deb", in a directory of remote
+// machine, and then yarn and serve from the npm package tarballs, such as those made by "npm pack yarn serve", in the
+// same directory
+func getCommandsInstallingNodeFromPackages(d *distro.Distro, nodePackagesDir string) []string {
+	return []string{
+		d.InstallLocalCommand(nodePackagesDir),
+		fmt.Sprintf("sudo npm install -g %s/*.tgz", nodePackagesDir),
 	}
 }
@@ -10,12 +10,42 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	DistSource       *string `mapstructure:"distSource" required:"true" cty:"distSource" hcl:"distSource"`
-	SslCertBase64    *string `mapstructure:"sslCertBase64" required:"true" cty:"sslCertBase64" hcl:"sslCertBase64"`
-	SslCertKeyBase64 *string `mapstructure:"sslCertKeyBase64" required:"true" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
-	AppDomain        *string `mapstructure:"appDomain" required:"true" cty:"appDomain" hcl:"appDomain"`
-	NodeVersion      *string `mapstructure:"nodeVersion" required:"false" cty:"nodeVersion" hcl:"nodeVersion"`
-	HomeDir          *string `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	DistSource                    *string           `mapstructure:"distSource" required:"false" cty:"distSource" hcl:"distSource"`
+	AppDomain                     *string           `mapstructure:"appDomain" required:"true" cty:"appDomain" hcl:"appDomain"`
+	ServeMode                     *string           `mapstructure:"serveMode" required:"false" cty:"serveMode" hcl:"serveMode"`
+	NodeVersion                   *string           `mapstructure:"nodeVersion" required:"false" cty:"nodeVersion" hcl:"nodeVersion"`
+	HomeDir                       *string           `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	AppPort                       *int              `mapstructure:"appPort" required:"false" cty:"appPort" hcl:"appPort"`
+	ServiceUser                   *string           `mapstructure:"serviceUser" required:"false" cty:"serviceUser" hcl:"serviceUser"`
+	SourceDir                     *string           `mapstructure:"sourceDir" required:"false" cty:"sourceDir" hcl:"sourceDir"`
+	SourceExcludes                []string          `mapstructure:"sourceExcludes" required:"false" cty:"sourceExcludes" hcl:"sourceExcludes"`
+	PackageManager                *string           `mapstructure:"packageManager" required:"false" cty:"packageManager" hcl:"packageManager"`
+	BuildCommand                  *string           `mapstructure:"buildCommand" required:"false" cty:"buildCommand" hcl:"buildCommand"`
+	BuildOutputDir                *string           `mapstructure:"buildOutputDir" required:"false" cty:"buildOutputDir" hcl:"buildOutputDir"`
+	RuntimeEnv                    map[string]string `mapstructure:"runtimeEnv" required:"false" cty:"runtimeEnv" hcl:"runtimeEnv"`
+	RuntimeEnvFormat              *string           `mapstructure:"runtimeEnvFormat" required:"false" cty:"runtimeEnvFormat" hcl:"runtimeEnvFormat"`
+	RuntimeEnvOnBoot              *bool             `mapstructure:"runtimeEnvOnBoot" required:"false" cty:"runtimeEnvOnBoot" hcl:"runtimeEnvOnBoot"`
+	Offline                       *bool             `mapstructure:"offline" required:"false" cty:"offline" hcl:"offline"`
+	NodePackagesSource            *string           `mapstructure:"nodePackagesSource" required:"false" cty:"nodePackagesSource" hcl:"nodePackagesSource"`
+	SslCertMode                   *string           `mapstructure:"sslCertMode" required:"false" cty:"sslCertMode" hcl:"sslCertMode"`
+	SslCertBase64                 *string           `mapstructure:"sslCertBase64" required:"false" cty:"sslCertBase64" hcl:"sslCertBase64"`
+	SslCertKeyBase64              *string           `mapstructure:"sslCertKeyBase64" required:"false" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
+	SslCertFile                   *string           `mapstructure:"sslCertFile" required:"false" cty:"sslCertFile" hcl:"sslCertFile"`
+	SslCertKeyFile                *string           `mapstructure:"sslCertKeyFile" required:"false" cty:"sslCertKeyFile" hcl:"sslCertKeyFile"`
+	SslCertEnv                    *string           `mapstructure:"sslCertEnv" required:"false" cty:"sslCertEnv" hcl:"sslCertEnv"`
+	SslCertKeyEnv                 *string           `mapstructure:"sslCertKeyEnv" required:"false" cty:"sslCertKeyEnv" hcl:"sslCertKeyEnv"`
+	SslChainFile                  *string           `mapstructure:"sslChainFile" required:"false" cty:"sslChainFile" hcl:"sslChainFile"`
+	SslCertExpiryWindowDays       *int              `mapstructure:"sslCertExpiryWindowDays" required:"false" cty:"sslCertExpiryWindowDays" hcl:"sslCertExpiryWindowDays"`
+	SslCertFailWithinExpiryWindow *bool             `mapstructure:"sslCertFailWithinExpiryWindow" required:"false" cty:"sslCertFailWithinExpiryWindow" hcl:"sslCertFailWithinExpiryWindow"`
+	SslCaCertBase64               *string           `mapstructure:"sslCaCertBase64" required:"false" cty:"sslCaCertBase64" hcl:"sslCaCertBase64"`
+	SslCaKeyBase64                *string           `mapstructure:"sslCaKeyBase64" required:"false" cty:"sslCaKeyBase64" hcl:"sslCaKeyBase64"`
+	SslSelfSignedValidityDays     *int              `mapstructure:"sslSelfSignedValidityDays" required:"false" cty:"sslSelfSignedValidityDays" hcl:"sslSelfSignedValidityDays"`
+	SslAcmeEmail                  *string           `mapstructure:"sslAcmeEmail" required:"false" cty:"sslAcmeEmail" hcl:"sslAcmeEmail"`
+	SslAcmeDirectoryUrl           *string           `mapstructure:"sslAcmeDirectoryUrl" required:"false" cty:"sslAcmeDirectoryUrl" hcl:"sslAcmeDirectoryUrl"`
+	SslAcmeDirectoryCaBase64      *string           `mapstructure:"sslAcmeDirectoryCaBase64" required:"false" cty:"sslAcmeDirectoryCaBase64" hcl:"sslAcmeDirectoryCaBase64"`
+	NginxPackagesSource           *string           `mapstructure:"nginxPackagesSource" required:"false" cty:"nginxPackagesSource" hcl:"nginxPackagesSource"`
+	DryRunDir                     *string           `mapstructure:"dryRunDir" required:"false" cty:"dryRunDir" hcl:"dryRunDir"`
+	DryRunDistro                  *string           `mapstructure:"dryRunDistro" required:"false" cty:"dryRunDistro" hcl:"dryRunDistro"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -30,12 +60,42 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"distSource":       &hcldec.AttrSpec{Name: "distSource", Type: cty.String, Required: false},
-		"sslCertBase64":    &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
-		"sslCertKeyBase64": &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
-		"appDomain":        &hcldec.AttrSpec{Name: "appDomain", Type: cty.String, Required: false},
-		"nodeVersion":      &hcldec.AttrSpec{Name: "nodeVersion", Type: cty.String, Required: false},
-		"homeDir":          &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"distSource":                    &hcldec.AttrSpec{Name: "distSource", Type: cty.String, Required: false},
+		"appDomain":                     &hcldec.AttrSpec{Name: "appDomain", Type: cty.String, Required: false},
+		"serveMode":                     &hcldec.AttrSpec{Name: "serveMode", Type: cty.String, Required: false},
+		"nodeVersion":                   &hcldec.AttrSpec{Name: "nodeVersion", Type: cty.String, Required: false},
+		"homeDir":                       &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"appPort":                       &hcldec.AttrSpec{Name: "appPort", Type: cty.Number, Required: false},
+		"serviceUser":                   &hcldec.AttrSpec{Name: "serviceUser", Type: cty.String, Required: false},
+		"sourceDir":                     &hcldec.AttrSpec{Name: "sourceDir", Type: cty.String, Required: false},
+		"sourceExcludes":                &hcldec.AttrSpec{Name: "sourceExcludes", Type: cty.List(cty.String), Required: false},
+		"packageManager":                &hcldec.AttrSpec{Name: "packageManager", Type: cty.String, Required: false},
+		"buildCommand":                  &hcldec.AttrSpec{Name: "buildCommand", Type: cty.String, Required: false},
+		"buildOutputDir":                &hcldec.AttrSpec{Name: "buildOutputDir", Type: cty.String, Required: false},
+		"runtimeEnv":                    &hcldec.AttrSpec{Name: "runtimeEnv", Type: cty.Map(cty.String), Required: false},
+		"runtimeEnvFormat":              &hcldec.AttrSpec{Name: "runtimeEnvFormat", Type: cty.String, Required: false},
+		"runtimeEnvOnBoot":              &hcldec.AttrSpec{Name: "runtimeEnvOnBoot", Type: cty.Bool, Required: false},
+		"offline":                       &hcldec.AttrSpec{Name: "offline", Type: cty.Bool, Required: false},
+		"nodePackagesSource":            &hcldec.AttrSpec{Name: "nodePackagesSource", Type: cty.String, Required: false},
+		"sslCertMode":                   &hcldec.AttrSpec{Name: "sslCertMode", Type: cty.String, Required: false},
+		"sslCertBase64":                 &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
+		"sslCertKeyBase64":              &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
+		"sslCertFile":                   &hcldec.AttrSpec{Name: "sslCertFile", Type: cty.String, Required: false},
+		"sslCertKeyFile":                &hcldec.AttrSpec{Name: "sslCertKeyFile", Type: cty.String, Required: false},
+		"sslCertEnv":                    &hcldec.AttrSpec{Name: "sslCertEnv", Type: cty.String, Required: false},
+		"sslCertKeyEnv":                 &hcldec.AttrSpec{Name: "sslCertKeyEnv", Type: cty.String, Required: false},
+		"sslChainFile":                  &hcldec.AttrSpec{Name: "sslChainFile", Type: cty.String, Required: false},
+		"sslCertExpiryWindowDays":       &hcldec.AttrSpec{Name: "sslCertExpiryWindowDays", Type: cty.Number, Required: false},
+		"sslCertFailWithinExpiryWindow": &hcldec.AttrSpec{Name: "sslCertFailWithinExpiryWindow", Type: cty.Bool, Required: false},
+		"sslCaCertBase64":               &hcldec.AttrSpec{Name: "sslCaCertBase64", Type: cty.String, Required: false},
+		"sslCaKeyBase64":                &hcldec.AttrSpec{Name: "sslCaKeyBase64", Type: cty.String, Required: false},
+		"sslSelfSignedValidityDays":     &hcldec.AttrSpec{Name: "sslSelfSignedValidityDays", Type: cty.Number, Required: false},
+		"sslAcmeEmail":                  &hcldec.AttrSpec{Name: "sslAcmeEmail", Type: cty.String, Required: false},
+		"sslAcmeDirectoryUrl":           &hcldec.AttrSpec{Name: "sslAcmeDirectoryUrl", Type: cty.String, Required: false},
+		"sslAcmeDirectoryCaBase64":      &hcldec.AttrSpec{Name: "sslAcmeDirectoryCaBase64", Type: cty.String, Required: false},
+		"nginxPackagesSource":           &hcldec.AttrSpec{Name: "nginxPackagesSource", Type: cty.String, Required: false},
+		"dryRunDir":                     &hcldec.AttrSpec{Name: "dryRunDir", Type: cty.String, Required: false},
+		"dryRunDistro":                  &hcldec.AttrSpec{Name: "dryRunDistro", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -0,0 +1,236 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+const (
+	RuntimeEnvFormatJs   string = "js"
+	RuntimeEnvFormatJson string = "json"
+)
+
+// RuntimeEnvFile The file in the image whose variables, in the format of a systemd EnvironmentFile such as
+// "REACT_APP_API_URL=https://api.mycompany.com", override the runtime environment on boot. It is meant to be written by
+// user-data, for example with the "write_files" module of cloud-init
+const RuntimeEnvFile string = "/etc/react/env"
+
+// RuntimeEnvGlobal The global variable that the "js" runtime environment is assigned to
+const RuntimeEnvGlobal string = "window._env_"
+
+const runtimeEnvUnitName string = "react-env-config"
+const runtimeEnvScriptDst string = "/usr/local/bin/" + runtimeEnvUnitName
+const runtimeEnvSystemdUnitDir string = "/etc/systemd/system"
+
+var runtimeEnvNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (c *Config) runtimeEnvFormat() string {
+	if c.RuntimeEnvFormat == "" {
+		return RuntimeEnvFormatJs
+	}
+
+	return c.RuntimeEnvFormat
+}
+
+// Returns the name of the runtime environment file in the served directory, i.e. "env-config.js" or "env-config.json"
+func (c *Config) runtimeEnvFilename() string {
+	return fmt.Sprintf("env-config.%s", c.runtimeEnvFormat())
+}
+
+func (c *Config) validateRuntimeEnv() []error {
+	var errs []error
+
+	for _, name := range shell.SortedKeys(c.RuntimeEnv) {
+		if !runtimeEnvNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("runtimeEnv: '%s' is not a valid environment variable name", name))
+		}
+	}
+
+	switch c.runtimeEnvFormat() {
+	case RuntimeEnvFormatJs, RuntimeEnvFormatJson:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"runtimeEnvFormat: unsupported format '%s', expected '%s' or '%s'",
+			c.RuntimeEnvFormat,
+			RuntimeEnvFormatJs,
+			RuntimeEnvFormatJson,
+		))
+	}
+
+	if len(c.RuntimeEnv) == 0 && (c.RuntimeEnvFormat != "" || c.RuntimeEnvOnBoot) {
+		errs = append(errs, fmt.Errorf("runtimeEnvFormat and runtimeEnvOnBoot require runtimeEnv"))
+	}
+
+	return errs
+}
+
+// Uploads the runtime environment, and the boot hook if configured, into the home directory and then installs them
+func (p *Provisioner) provisionRuntimeEnv(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, d *distro.Distro, servedDir string) error {
+	if len(p.config.RuntimeEnv) == 0 {
+		return nil
+	}
+	if p.config.RuntimeEnvOnBoot && !d.Systemd() {
+		return fmt.Errorf("runtimeEnvOnBoot installs a systemd service, which '%s' does not run", d.ID)
+	}
+
+	content := getRuntimeEnvContent(p.config.RuntimeEnv, p.config.runtimeEnvFormat())
+	if err := ssl.UploadContent(p.config.ctx, ui, communicator, content, filepath.Join(p.config.HomeDir, p.config.runtimeEnvFilename())); err != nil {
+		return err
+	}
+
+	steps := []shell.Step{getStepInstallingRuntimeEnv(p.config.HomeDir, p.config.runtimeEnvFilename(), servedDir)}
+
+	if p.config.RuntimeEnvOnBoot {
+		target := filepath.Join(servedDir, p.config.runtimeEnvFilename())
+		script := getRuntimeEnvScript(p.config.RuntimeEnv, p.config.runtimeEnvFormat(), target)
+		if err := ssl.UploadContent(p.config.ctx, ui, communicator, script, filepath.Join(p.config.HomeDir, runtimeEnvUnitName)); err != nil {
+			return err
+		}
+		if err := ssl.UploadContent(p.config.ctx, ui, communicator, getRuntimeEnvUnit(!p.config.isStatic()), filepath.Join(p.config.HomeDir, runtimeEnvUnitName+".service")); err != nil {
+			return err
+		}
+
+		steps = append(steps, getStepInstallingRuntimeEnvBootHook(p.config.HomeDir))
+	}
+
+	return shell.Provision(ctx, ui, communicator, steps)
+}
+
+// Returns the runtime environment with the build-time values, either as a script assigning them to RuntimeEnvGlobal,
+// which index.html loads with <script src="/env-config.js"></script> ahead of the bundle, or as JSON, which the app
+// fetches
+func getRuntimeEnvContent(env map[string]string, format string) string {
+	// Marshalling a map of strings never fails. Keys come out sorted
+	values, _ := json.MarshalIndent(env, "", "  ")
+
+	if format == RuntimeEnvFormatJson {
+		return string(values) + "\n"
+	}
+
+	return fmt.Sprintf("%s = %s;\n", RuntimeEnvGlobal, values)
+}
+
+func getStepInstallingRuntimeEnv(homeDir string, filename string, servedDir string) shell.Step {
+	return shell.Step{
+		Name: "Installing runtime environment",
+		Commands: []string{
+			fmt.Sprintf("sudo install -D -m 0644 %s %s", filepath.Join(homeDir, filename), filepath.Join(servedDir, filename)),
+			fmt.Sprintf("rm -f %s", filepath.Join(homeDir, filename)),
+		},
+	}
+}
+
+// Returns the step that installs the boot hook and enables it. systemd is reloaded only if it is running, so that
+// images can be built in containers as well
+func getStepInstallingRuntimeEnvBootHook(homeDir string) shell.Step {
+	return shell.Step{
+		Name: "Installing runtime environment boot hook",
+		Commands: []string{
+			fmt.Sprintf("sudo install -m 0755 %s %s", filepath.Join(homeDir, runtimeEnvUnitName), runtimeEnvScriptDst),
+			fmt.Sprintf("sudo mv %s %s/", filepath.Join(homeDir, runtimeEnvUnitName+".service"), runtimeEnvSystemdUnitDir),
+			fmt.Sprintf("rm -f %s", filepath.Join(homeDir, runtimeEnvUnitName)),
+			"if [ -d /run/systemd/system ]; then sudo systemctl daemon-reload; fi",
+			fmt.Sprintf("sudo systemctl enable %s", runtimeEnvUnitName),
+		},
+	}
+}
+
+// Returns the systemd unit that regenerates the runtime environment on every boot, after user-data has been applied by
+// cloud-init and before Nginx starts serving. In "proxy" mode, the React service is made to want the unit and to start
+// after it as well, so that serve never serves the environment of the build
+func getRuntimeEnvUnit(proxy bool) string {
+	services := "nginx.service"
+	wantedBy := "multi-user.target"
+	if proxy {
+		services += " " + ServiceName + ".service"
+		wantedBy += " " + ServiceName + ".service"
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=Generate runtime environment of React app
+After=network-online.target cloud-init.service
+Wants=network-online.target
+Before=%s
+
+[Service]
+Type=oneshot
+EnvironmentFile=-%s
+ExecStart=%s
+
+[Install]
+WantedBy=%s
+`, services, RuntimeEnvFile, runtimeEnvScriptDst, wantedBy)
+}
+
+// Returns the POSIX shell script that regenerates the runtime environment at target. Each variable takes its value from
+// the environment of the script, which includes RuntimeEnvFile, and falls back to its build-time value otherwise
+func getRuntimeEnvScript(env map[string]string, format string, target string) string {
+	type variable struct {
+		Name    string
+		Default string
+	}
+
+	var variables []variable
+	for _, name := range shell.SortedKeys(env) {
+		variables = append(variables, variable{name, shell.Quote(env[name])})
+	}
+
+	opening, closing := RuntimeEnvGlobal+" = {", "};"
+	if format == RuntimeEnvFormatJson {
+		opening, closing = "{", "}"
+	}
+
+	var scriptConfigs = struct {
+		Target    string
+		Open      string
+		Close     string
+		Variables []variable
+	}{target, opening, closing, variables}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("Runtime Environment Script").Parse(`#!/bin/sh
+# Regenerates {{.Target}} from the environment on boot
+set -e
+
+# Prints a value as the content of a JSON string
+escape() {
+    printf '%s' "$1" | sed -e 's/\\/\\\\/g' -e 's/"/\\"/g' -e 's/\t/\\t/g' | awk 'NR > 1 { printf "\\n" } { printf "%s", $0 }'
+}
+
+# Prints a variable as a JSON member, taking its value from the environment or else from the build
+member() {
+    value=$(printenv "$1" || printf '%s' "$2")
+    printf '%s\n  "%s": "%s"' "$separator" "$1" "$(escape "$value")"
+    separator=,
+}
+
+tmp=$(mktemp "{{.Target}}.XXXXXX")
+separator=
+{
+    printf '%s' '{{.Open}}'
+{{- range .Variables}}
+    member {{.Name}} {{.Default}}
+{{- end}}
+    printf '\n%s\n' '{{.Close}}'
+} > "$tmp"
+chmod 0644 "$tmp"
+mv "$tmp" "{{.Target}}"
+`))
+	if err := t.Execute(&buf, scriptConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
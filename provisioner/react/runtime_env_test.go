@@ -0,0 +1,157 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"context"
+	"encoding/json"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetRuntimeEnvContent(t *testing.T) {
+	env := map[string]string{"REACT_APP_API_URL": "https://api.mycompany.com", "REACT_APP_TITLE": `"Paion" </script>`}
+
+	expectedJs := `window._env_ = {
+  "REACT_APP_API_URL": "https://api.mycompany.com",
+  "REACT_APP_TITLE": "\"Paion\" \u003c/script\u003e"
+};
+`
+	if actual := getRuntimeEnvContent(env, RuntimeEnvFormatJs); actual != expectedJs {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expectedJs, actual)
+	}
+
+	var actual map[string]string
+	if err := json.Unmarshal([]byte(getRuntimeEnvContent(env, RuntimeEnvFormatJson)), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env, actual) {
+		t.Errorf("Expected JSON of %v, got %v", env, actual)
+	}
+}
+
+func TestGetRuntimeEnvScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no POSIX shell to run the script with")
+	}
+
+	target := filepath.Join(t.TempDir(), "env-config.json")
+	env := map[string]string{"REACT_APP_API_URL": "https://api.staging.mycompany.com", "REACT_APP_TITLE": "it's staging"}
+	script := filepath.Join(t.TempDir(), "react-env-config")
+	if err = os.WriteFile(script, []byte(getRuntimeEnvScript(env, RuntimeEnvFormatJson, target)), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(sh, script)
+	cmd.Env = append(os.Environ(), "REACT_APP_API_URL=https://api.mycompany.com/\"v1\"\\\ttab\nline")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Expected script to succeed, got: %v\n%s", err, output)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[string]string
+	if err = json.Unmarshal(content, &actual); err != nil {
+		t.Fatalf("Expected valid JSON, got: %v\n%s", err, content)
+	}
+
+	expected := map[string]string{"REACT_APP_API_URL": "https://api.mycompany.com/\"v1\"\\\ttab\nline", "REACT_APP_TITLE": "it's staging"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestProvisionRuntimeEnv(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"distSource":       t.TempDir(),
+		"appDomain":        "app.mycompany.com",
+		"sslCertMode":      "self-signed",
+		"serveMode":        ServeModeStatic,
+		"runtimeEnv":       map[string]string{"REACT_APP_API_URL": "https://api.mycompany.com"},
+		"runtimeEnvOnBoot": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	envConfig := communicator.Uploaded("/home/ubuntu/env-config.js")
+	if envConfig == nil || !strings.HasPrefix(string(envConfig.Content), "window._env_ = {") {
+		t.Errorf("Expected env-config.js to be uploaded, got: %v", envConfig)
+	}
+	script := communicator.Uploaded("/home/ubuntu/react-env-config")
+	if script == nil || !strings.Contains(string(script.Content), `mv "$tmp" "/var/www/react/env-config.js"`) {
+		t.Errorf("Expected boot hook to regenerate env-config.js in web root, got: %v", script)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 5 {
+		t.Fatalf("Expected 5 steps, got: %s", scripts)
+	}
+	if !strings.Contains(scripts[1], "sudo install -D -m 0644 /home/ubuntu/env-config.js /var/www/react/env-config.js\n") {
+		t.Errorf("Expected env-config.js to be installed into web root, got:\n%s", scripts[1])
+	}
+	if !strings.Contains(scripts[2], "sudo systemctl enable react-env-config\n") {
+		t.Errorf("Expected boot hook to be enabled, got:\n%s", scripts[2])
+	}
+}
+
+func TestGetRuntimeEnvUnit(t *testing.T) {
+	data := []struct {
+		name             string
+		proxy            bool
+		expectedSnippets []string
+	}{
+		{"static", false, []string{"Before=nginx.service\n", "WantedBy=multi-user.target\n"}},
+		{"proxy", true, []string{"Before=nginx.service react.service\n", "WantedBy=multi-user.target react.service\n"}},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			unit := getRuntimeEnvUnit(d.proxy)
+			for _, snippet := range d.expectedSnippets {
+				if !strings.Contains(unit, snippet) {
+					t.Errorf("Expected '%s' in unit:\n%s", snippet, unit)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRuntimeEnv(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"no runtime environment", Config{}, false},
+		{"JSON on boot", Config{RuntimeEnv: map[string]string{"REACT_APP_API_URL": "x"}, RuntimeEnvFormat: "json", RuntimeEnvOnBoot: true}, false},
+		{"invalid name", Config{RuntimeEnv: map[string]string{"REACT-APP": "x"}}, true},
+		{"unsupported format", Config{RuntimeEnv: map[string]string{"REACT_APP_API_URL": "x"}, RuntimeEnvFormat: "yaml"}, true},
+		{"boot hook without variables", Config{RuntimeEnvOnBoot: true}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateRuntimeEnv()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
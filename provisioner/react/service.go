@@ -0,0 +1,158 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+// DefaultAppPort The port that serve listens on behind Nginx in "proxy" mode, unless another one is configured
+const DefaultAppPort int = 3000
+
+// DefaultServiceUser The system user that runs serve in "proxy" mode, unless another one is configured
+const DefaultServiceUser string = "react"
+
+// ServiceName The name of the systemd service that runs serve in "proxy" mode
+const ServiceName string = "react"
+
+// AppDir The directory that holds the dist served by serve in "proxy" mode
+const AppDir string = "/opt/react"
+
+const serviceSystemdUnitDir string = "/etc/systemd/system"
+
+var serviceUserPattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+func (c *Config) appPort() int {
+	if c.AppPort == 0 {
+		return DefaultAppPort
+	}
+
+	return c.AppPort
+}
+
+func (c *Config) serviceUser() string {
+	if c.ServiceUser == "" {
+		return DefaultServiceUser
+	}
+
+	return c.ServiceUser
+}
+
+func (c *Config) validateService() []error {
+	if c.isStatic() {
+		if c.AppPort != 0 || c.ServiceUser != "" {
+			return []error{fmt.Errorf("appPort and serviceUser: serve does not run in '%s' mode", ServeModeStatic)}
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.appPort() < 1 || c.appPort() > 65535 {
+		errs = append(errs, fmt.Errorf("appPort: %d is not a valid port", c.AppPort))
+	}
+	if !serviceUserPattern.MatchString(c.serviceUser()) {
+		errs = append(errs, fmt.Errorf("serviceUser: '%s' is not a valid Linux user name", c.ServiceUser))
+	}
+
+	return errs
+}
+
+// Moves the dist uploaded into the home directory under AppDir and installs the systemd service that serves it there
+func (p *Provisioner) provisionService(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, d *distro.Distro) error {
+	if !d.Systemd() {
+		return fmt.Errorf("serve runs as a systemd service in '%s' mode, which '%s' does not run; use '%s' mode", ServeModeProxy, d.ID, ServeModeStatic)
+	}
+
+	unit := getServiceUnit(p.config.serviceUser(), p.config.appPort())
+	if err := ssl.UploadContent(p.config.ctx, ui, communicator, unit, filepath.Join(p.config.HomeDir, ServiceName+".service")); err != nil {
+		return err
+	}
+
+	return shell.Provision(ctx, ui, communicator, []shell.Step{
+		getStepCreatingServiceUser(p.config.serviceUser()),
+		getStepInstallingService(p.config.HomeDir, p.config.serviceUser()),
+	})
+}
+
+// Returns the systemd unit that runs serve as the service user on the loopback interface, where only Nginx reaches it,
+// and restarts it whenever it fails. Its output goes to journald, so that it can be read with "journalctl -u react"
+func getServiceUnit(user string, port int) string {
+	var serviceConfigs = struct {
+		Name    string
+		User    string
+		AppDir  string
+		DistDir string
+		Port    int
+	}{ServiceName, user, AppDir, getServedDistDir(), port}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("React Service").Parse(`[Unit]
+Description=React app served by serve
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User={{.User}}
+Group={{.User}}
+WorkingDirectory={{.AppDir}}
+Environment=NODE_ENV=production
+ExecStart=/usr/bin/env serve -s {{.DistDir}} -l tcp://127.0.0.1:{{.Port}} --no-clipboard
+Restart=on-failure
+RestartSec=5
+NoNewPrivileges=true
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier={{.Name}}
+
+[Install]
+WantedBy=multi-user.target
+`))
+	if err := t.Execute(&buf, serviceConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
+
+// Returns the directory that serve serves the dist from in "proxy" mode
+func getServedDistDir() string {
+	return filepath.Join(AppDir, "dist")
+}
+
+// Returns the step that creates the system user running serve, unless it exists already
+func getStepCreatingServiceUser(user string) shell.Step {
+	return shell.Step{
+		Name:     fmt.Sprintf("Creating service user %s", user),
+		Commands: []string{fmt.Sprintf("sudo useradd --system --no-create-home --shell /usr/sbin/nologin %s", user)},
+		Unless:   fmt.Sprintf("id -u %s", user),
+	}
+}
+
+// Returns the step that moves the uploaded dist and systemd unit into place, replacing the dist of any earlier build, and
+// enables the service at boot. The service is not started during the build. systemd is reloaded only if it is running,
+// so that images can be built in containers as well
+func getStepInstallingService(homeDir string, user string) shell.Step {
+	return shell.Step{
+		Name: "Installing React app systemd service",
+		Commands: []string{
+			fmt.Sprintf("sudo mkdir -p %s", AppDir),
+			fmt.Sprintf("sudo rm -rf %s", getServedDistDir()),
+			fmt.Sprintf("sudo mv %s %s", filepath.Join(homeDir, "dist"), getServedDistDir()),
+			fmt.Sprintf("sudo chown -R %s:%s %s", user, user, AppDir),
+			fmt.Sprintf("sudo mv %s %s/", filepath.Join(homeDir, ServiceName+".service"), serviceSystemdUnitDir),
+			"if [ -d /run/systemd/system ]; then sudo systemctl daemon-reload; fi",
+			fmt.Sprintf("sudo systemctl enable %s", ServiceName),
+		},
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"strings"
+	"testing"
+)
+
+func TestGetServiceUnit(t *testing.T) {
+	unit := getServiceUnit("frontend", 4000)
+
+	expectedLines := []string{
+		"User=frontend",
+		"Group=frontend",
+		"WorkingDirectory=/opt/react",
+		"ExecStart=/usr/bin/env serve -s /opt/react/dist -l tcp://127.0.0.1:4000 --no-clipboard",
+		"Restart=on-failure",
+		"StandardOutput=journal",
+		"WantedBy=multi-user.target",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Expected '%s' in systemd unit:\n%s", line, unit)
+		}
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"defaults", Config{}, false},
+		{"custom port and user", Config{AppPort: 4000, ServiceUser: "frontend"}, false},
+		{"invalid port", Config{AppPort: 70000}, true},
+		{"invalid user", Config{ServiceUser: "Front End"}, true},
+		{"port in static mode", Config{ServeMode: ServeModeStatic, AppPort: 4000}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateService()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestProvisionProxyWithRuntimeEnv(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"distSource":  t.TempDir(),
+		"appDomain":   "app.mycompany.com",
+		"sslCertMode": "self-signed",
+		"appPort":     4000,
+		"runtimeEnv":  map[string]string{"REACT_APP_API_URL": "https://api.mycompany.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(strings.Join(communicator.Scripts(), "\n"), "sudo install -D -m 0644 /home/ubuntu/env-config.js /opt/react/dist/env-config.js\n") {
+		t.Errorf("Expected env-config.js to be installed into the dist served by serve, got: %s", communicator.Scripts())
+	}
+
+	nginxConfig := communicator.Uploaded("/home/ubuntu/nginx-ssl.conf")
+	if nginxConfig == nil || !strings.Contains(string(nginxConfig.Content), "proxy_pass http://127.0.0.1:4000;") {
+		t.Errorf("Expected Nginx to proxy to port 4000, got: %v", nginxConfig)
+	}
+}
+
+func TestProvisionProxyWithoutSystemd(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"distSource":  t.TempDir(),
+		"appDomain":   "app.mycompany.com",
+		"sslCertMode": "self-signed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	communicator.OsRelease = "ID=alpine\nVERSION_ID=3.19.1\n"
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err == nil {
+		t.Error("Expected proxy mode to fail on a distribution without systemd")
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	PackageManagerYarn string = "yarn"
+	PackageManagerNpm  string = "npm"
+	PackageManagerPnpm string = "pnpm"
+)
+
+// DefaultBuildOutputDir The directory, relative to sourceDir, that the build writes the dist into, unless another one
+// is configured. It is where Create React App writes to
+const DefaultBuildOutputDir string = "build"
+
+const sourceArchiveFilename string = "react-src.tar.gz"
+const sourceDirName string = "react-src"
+
+// Always excluded from the uploaded source, since they are either reinstalled on the image or not needed for the build
+var defaultSourceExcludes = []string{".git/", "node_modules/"}
+
+// Returns the package manager of the source, which is the configured one or else the one whose lockfile the source has
+func (c *Config) packageManager() string {
+	if c.PackageManager != "" {
+		return c.PackageManager
+	}
+
+	switch {
+	case fileExists(filepath.Join(c.SourceDir, "yarn.lock")):
+		return PackageManagerYarn
+	case fileExists(filepath.Join(c.SourceDir, "pnpm-lock.yaml")):
+		return PackageManagerPnpm
+	default:
+		return PackageManagerNpm
+	}
+}
+
+func (c *Config) buildCommand() string {
+	if c.BuildCommand != "" {
+		return c.BuildCommand
+	}
+
+	return fmt.Sprintf("%s run build", c.packageManager())
+}
+
+func (c *Config) buildOutputDir() string {
+	if c.BuildOutputDir == "" {
+		return DefaultBuildOutputDir
+	}
+
+	return c.BuildOutputDir
+}
+
+func (c *Config) validateSource() []error {
+	if (c.DistSource == "") == (c.SourceDir == "") {
+		return []error{fmt.Errorf("exactly one of distSource and sourceDir is required")}
+	}
+	if c.SourceDir == "" {
+		if c.PackageManager != "" || c.BuildCommand != "" || c.BuildOutputDir != "" || len(c.SourceExcludes) > 0 {
+			return []error{fmt.Errorf("packageManager, buildCommand, buildOutputDir and sourceExcludes require sourceDir")}
+		}
+		return nil
+	}
+
+	var errs []error
+
+	if c.Offline {
+		errs = append(errs, fmt.Errorf("sourceDir: dependencies are installed from the npm registry, which offline builds cannot reach; use distSource"))
+	}
+	if !fileExists(filepath.Join(c.SourceDir, "package.json")) {
+		errs = append(errs, fmt.Errorf("sourceDir: '%s' has no package.json", c.SourceDir))
+	}
+
+	switch c.PackageManager {
+	case "", PackageManagerYarn, PackageManagerNpm, PackageManagerPnpm:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"packageManager: unsupported package manager '%s', expected one of '%s', '%s' and '%s'",
+			c.PackageManager,
+			PackageManagerYarn,
+			PackageManagerNpm,
+			PackageManagerPnpm,
+		))
+	}
+
+	if output := filepath.Clean(c.buildOutputDir()); filepath.IsAbs(output) || output == "." || strings.HasPrefix(output, "..") {
+		errs = append(errs, fmt.Errorf("buildOutputDir: '%s' is not a directory inside sourceDir", c.BuildOutputDir))
+	}
+
+	return errs
+}
+
+// Uploads the source directory as a tarball into the home directory and builds it there into the dist
+func (p *Provisioner) provisionSource(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
+	archive, err := archiveSource(p.config.SourceDir, p.config.SourceExcludes)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+
+	err = file.Provision(p.config.ctx, ui, communicator, archive, filepath.Join(p.config.HomeDir, sourceArchiveFilename))
+	if err != nil {
+		return err
+	}
+
+	return shell.Provision(ctx, ui, communicator, []shell.Step{getStepBuildingSource(p.config.HomeDir, p.config)})
+}
+
+// Returns the command that installs the dependencies of the source exactly as locked
+func getInstallCommand(packageManager string, sourceDir string) string {
+	switch packageManager {
+	case PackageManagerYarn:
+		return "yarn install --frozen-lockfile"
+	case PackageManagerPnpm:
+		return "pnpm install --frozen-lockfile"
+	default:
+		if fileExists(filepath.Join(sourceDir, "package-lock.json")) || fileExists(filepath.Join(sourceDir, "npm-shrinkwrap.json")) {
+			return "npm ci"
+		}
+		return "npm install"
+	}
+}
+
+// Returns the step that unpacks the uploaded source, installs its dependencies and builds it, replacing the dist in the
+// home directory with the build output
+func getStepBuildingSource(homeDir string, config Config) shell.Step {
+	srcDir := filepath.Join(homeDir, sourceDirName)
+	archive := filepath.Join(homeDir, sourceArchiveFilename)
+	distDir := filepath.Join(homeDir, "dist")
+
+	var commands []string
+	if config.packageManager() == PackageManagerPnpm {
+		commands = append(commands, "sudo npm install -g pnpm")
+	}
+
+	return shell.Step{
+		Name: "Building React app from source",
+		Commands: append(
+			commands,
+			fmt.Sprintf("rm -rf %s", srcDir),
+			fmt.Sprintf("mkdir -p %s", srcDir),
+			fmt.Sprintf("tar -xzf %s -C %s", archive, srcDir),
+			fmt.Sprintf("rm -f %s", archive),
+			fmt.Sprintf("cd %s", srcDir),
+			getInstallCommand(config.packageManager(), config.SourceDir),
+			config.buildCommand(),
+			fmt.Sprintf("rm -rf %s", distDir),
+			fmt.Sprintf("cp -r %s %s", filepath.Join(srcDir, config.buildOutputDir()), distDir),
+		),
+	}
+}
+
+// Packs the source directory into a gzipped tarball, leaving out everything that matches the excludes, the patterns in
+// the .gitignore at the root of the source directory, or defaultSourceExcludes.
+//
+// Returns:
+// The path to the tarball, which the caller removes
+func archiveSource(sourceDir string, excludes []string) (string, error) {
+	patterns := append(append([]string{}, defaultSourceExcludes...), excludes...)
+	gitignore, err := readIgnoreFile(filepath.Join(sourceDir, ".gitignore"))
+	if err != nil {
+		return "", err
+	}
+	rules := parseIgnoreRules(append(patterns, gitignore...))
+
+	archive, err := os.CreateTemp("", "react-src-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("error creating source archive: %s", err)
+	}
+	defer archive.Close()
+
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(sourceDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rules.ignores(rel, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return addToArchive(tw, path, rel, entry)
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		os.Remove(archive.Name())
+		return "", fmt.Errorf("error archiving source directory '%s': %s", sourceDir, err)
+	}
+
+	return archive.Name(), nil
+}
+
+func addToArchive(tw *tar.Writer, path string, name string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if entry.IsDir() {
+		header.Name += "/"
+	}
+	if err = tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// An exclude pattern in the format of .gitignore
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+type ignoreRules []ignoreRule
+
+// Parses patterns in the format of .gitignore. Blank lines and comments are skipped. A leading "!" re-includes what an
+// earlier pattern excludes, a trailing "/" matches directories only, and a pattern containing a "/" other than a
+// trailing one is relative to the root, whereas any other pattern matches at any depth. "*" and "?" match within a path
+// segment, and "**" across segments
+func parseIgnoreRules(patterns []string) ignoreRules {
+	var rules ignoreRules
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(pattern, "!") {
+			rule.negate = true
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+
+		anchored := strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+
+		prefix := "^(.*/)?"
+		if anchored {
+			prefix = "^"
+		}
+		rule.pattern = regexp.MustCompile(prefix + globToRegexp(pattern) + "$")
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// Returns whether a path relative to the root, with "/" as separator, is excluded. The last matching pattern wins
+func (rules ignoreRules) ignores(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			re.WriteString("[^/]*")
+		case glob[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		}
+	}
+
+	return re.String()
+}
+
+// Returns the lines of an ignore file, or none if the file does not exist
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
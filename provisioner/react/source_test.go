@@ -0,0 +1,263 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package react
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]string{
+		"# comment",
+		"",
+		"node_modules/",
+		"*.log",
+		"!keep.log",
+		"/coverage",
+		"docs/**/*.md",
+		"build/",
+	})
+
+	data := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"node_modules", true, true},
+		{"packages/ui/node_modules", true, true},
+		{"node_modules", false, false},
+		{"npm-debug.log", false, true},
+		{"logs/server.log", false, true},
+		{"logs/keep.log", false, false},
+		{"coverage", true, true},
+		{"src/coverage", true, false},
+		{"docs/guide/setup.md", false, true},
+		{"docs/README.md", false, true},
+		{"README.md", false, false},
+		{"build", true, true},
+		{"src/App.js", false, false},
+	}
+
+	for _, d := range data {
+		t.Run(d.path, func(t *testing.T) {
+			if actual := rules.ignores(d.path, d.isDir); actual != d.expected {
+				t.Errorf("Expected '%s' to be ignored: %t, got: %t", d.path, d.expected, actual)
+			}
+		})
+	}
+}
+
+func TestArchiveSource(t *testing.T) {
+	sourceDir := newTestSource(t, map[string]string{
+		"package.json":              "{}",
+		"yarn.lock":                 "",
+		".gitignore":                "/build\n*.log\n!important.log\n",
+		"src/App.js":                "export default App",
+		"src/debug.log":             "",
+		"src/important.log":         "",
+		"build/index.html":          "",
+		"node_modules/react/a.js":   "",
+		".git/HEAD":                 "",
+		"public/secrets/token.json": "",
+	})
+
+	archive, err := archiveSource(sourceDir, []string{"public/secrets/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(archive)
+
+	content, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{".gitignore", "package.json", "public/", "src/", "src/App.js", "src/important.log", "yarn.lock"}
+	if actual := listArchive(t, content); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected archive entries %s, got: %s", expected, actual)
+	}
+}
+
+func TestGetStepBuildingSource(t *testing.T) {
+	data := []struct {
+		name             string
+		files            map[string]string
+		config           Config
+		expectedCommands []string
+	}{
+		{
+			"yarn",
+			map[string]string{"package.json": "{}", "yarn.lock": ""},
+			Config{},
+			[]string{"yarn install --frozen-lockfile", "yarn run build", "cp -r /home/ubuntu/react-src/build /home/ubuntu/dist"},
+		},
+		{
+			"npm with lockfile",
+			map[string]string{"package.json": "{}", "package-lock.json": "{}"},
+			Config{BuildOutputDir: "dist"},
+			[]string{"npm ci", "npm run build", "cp -r /home/ubuntu/react-src/dist /home/ubuntu/dist"},
+		},
+		{
+			"npm without lockfile",
+			map[string]string{"package.json": "{}"},
+			Config{BuildCommand: "npm run build:prod"},
+			[]string{"npm install", "npm run build:prod"},
+		},
+		{
+			"pnpm",
+			map[string]string{"package.json": "{}", "pnpm-lock.yaml": ""},
+			Config{},
+			[]string{"sudo npm install -g pnpm", "pnpm install --frozen-lockfile", "pnpm run build"},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			d.config.SourceDir = newTestSource(t, d.files)
+			commands := getStepBuildingSource("/home/ubuntu", d.config).Commands
+
+			for _, expected := range d.expectedCommands {
+				if !containsString(commands, expected) {
+					t.Errorf("Expected command '%s', got: %s", expected, commands)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	source := newTestSource(t, map[string]string{"package.json": "{}"})
+	empty := t.TempDir()
+
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"dist", Config{DistSource: empty}, false},
+		{"source", Config{SourceDir: source, PackageManager: "pnpm", BuildOutputDir: "out/web"}, false},
+		{"neither dist nor source", Config{}, true},
+		{"both dist and source", Config{DistSource: empty, SourceDir: source}, true},
+		{"build options without source", Config{DistSource: empty, BuildCommand: "yarn build"}, true},
+		{"no package.json", Config{SourceDir: empty}, true},
+		{"unsupported package manager", Config{SourceDir: source, PackageManager: "bun"}, true},
+		{"output outside source", Config{SourceDir: source, BuildOutputDir: "../dist"}, true},
+		{"offline", Config{SourceDir: source, Offline: true}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateSource()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestProvisionFromSource(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"sourceDir":   newTestSource(t, map[string]string{"package.json": "{}", "yarn.lock": "", "src/App.js": ""}),
+		"appDomain":   "app.mycompany.com",
+		"sslCertMode": "self-signed",
+		"serveMode":   ServeModeStatic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(communicator.DirUploads) != 0 {
+		t.Errorf("Expected no dist to be uploaded, got: %+v", communicator.DirUploads)
+	}
+
+	archive := communicator.Uploaded("/home/ubuntu/react-src.tar.gz")
+	if archive == nil {
+		t.Fatal("Expected source archive to be uploaded")
+	}
+	if entries := listArchive(t, archive.Content); !containsString(entries, "src/App.js") {
+		t.Errorf("Expected source in archive, got: %s", entries)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 6 {
+		t.Fatalf("Expected Node.js installation, build, dist installation and SSL setup, got: %s", scripts)
+	}
+	if !strings.Contains(scripts[1], "https://deb.nodesource.com/setup_18.x") {
+		t.Errorf("Expected Node.js to be installed before the build, got:\n%s", scripts[1])
+	}
+	if !strings.Contains(scripts[2], "yarn install --frozen-lockfile\n") {
+		t.Errorf("Expected source to be built with yarn, got:\n%s", scripts[2])
+	}
+	if !strings.Contains(scripts[3], "sudo cp -r /home/ubuntu/dist/. /var/www/react/\n") {
+		t.Errorf("Expected build output to be installed into web root, got:\n%s", scripts[3])
+	}
+}
+
+// Returns a temporary directory with the files, keyed by path relative to the directory
+func newTestSource(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// Returns the sorted entry names of a gzipped tarball
+func listArchive(t *testing.T, content []byte) []string {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -23,8 +23,7 @@ build {
   provisioner "paion-data-react-provisioner" {
     distSource       = "/my/path/to/dist"
     homeDir          = "/"
-    sslCertBase64    = "YXNkZnNnaHRkeWhyZXJ3ZGZydGV3ZHNmZ3RoeTY0cmV3ZGZyZWd0cmV3d2ZyZw=="
-    sslCertKeyBase64 = "MzI0NXRnZjk4dmJoIGNsO2VbNDM1MHRdzszNDM1b2l0cmo="
+    sslCertMode      = "self-signed"
     appDomain        = "app.mycompany.com"
   }
 }
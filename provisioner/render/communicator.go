@@ -0,0 +1,193 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const manifestFilename string = "manifest.json"
+const scriptsDir string = "scripts"
+const filesDir string = "files"
+
+var nonAlphanumeric = regexp.MustCompile("[^a-z0-9]+")
+
+// Manifest Everything a provisioner would have applied to remote machine, in order
+type Manifest struct {
+	Distro   string   `json:"distro"`
+	Steps    []Step   `json:"steps"`
+	Uploads  []Upload `json:"uploads"`
+	Commands []string `json:"commands,omitempty"`
+}
+
+// Step A step of the shell provisioner, whose script is rendered into File
+type Step struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	// Guards The commands checking whether the step is already satisfied, in which case it would be skipped
+	Guards []string `json:"guards,omitempty"`
+}
+
+// Upload A file or directory that would be uploaded to Destination in remote machine
+type Upload struct {
+	Destination string `json:"destination"`
+	// File The rendered copy of an uploaded file, relative to the rendered directory
+	File string `json:"file,omitempty"`
+	// Source The local directory whose contents would be uploaded. Directories are not copied into the rendered one
+	Source string `json:"source,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+}
+
+// Communicator A packersdk.Communicator that renders into a local directory instead of provisioning a machine. It
+// answers the distribution detection with the /etc/os-release of the distribution rendered for, the architecture
+// detection with x86_64, and records all other commands with an exit status of 0
+type Communicator struct {
+	dir       string
+	osRelease string
+	manifest  Manifest
+	uploads   []renderedFile
+}
+
+// A file uploaded to the Communicator, which is written into the rendered directory only by WriteManifest
+type renderedFile struct {
+	file    string
+	content string
+	mode    os.FileMode
+}
+
+// NewCommunicator Returns a Communicator that renders into dir for the provided distribution, such as "ubuntu"
+func NewCommunicator(dir string, distroID string) (*Communicator, error) {
+	osRelease, err := getOsRelease(distroID)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := distro.Parse(osRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating dry run directory '%s': %s", dir, err)
+	}
+
+	return &Communicator{dir: dir, osRelease: osRelease, manifest: Manifest{Distro: d.ID}}, nil
+}
+
+// Manifest Returns what has been rendered so far
+func (c *Communicator) Manifest() Manifest {
+	return c.manifest
+}
+
+// WriteManifest Writes the uploaded files into "files/" and the manifest into "manifest.json" of the rendered directory.
+// Sensitive values are masked at this point, rather than on upload, because provisioners register them with
+// shell.Provision, which runs after the files containing them have been uploaded
+func (c *Communicator) WriteManifest() error {
+	for _, upload := range c.uploads {
+		if err := c.write(upload.file, packersdk.LogSecretFilter.FilterString(upload.content), upload.mode); err != nil {
+			return err
+		}
+	}
+
+	for i, command := range c.manifest.Commands {
+		c.manifest.Commands[i] = packersdk.LogSecretFilter.FilterString(command)
+	}
+
+	manifest, err := json.MarshalIndent(c.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(c.dir, manifestFilename), append(manifest, '\n'), 0644)
+}
+
+// RenderStep Writes the script of a step into "scripts/", numbered by the order of steps
+func (c *Communicator) RenderStep(name string, guards []string, script string) error {
+	slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	file := filepath.Join(scriptsDir, fmt.Sprintf("%02d-%s.sh", len(c.manifest.Steps)+1, slug))
+
+	if err := c.write(file, script, 0755); err != nil {
+		return err
+	}
+
+	c.manifest.Steps = append(c.manifest.Steps, Step{Name: name, File: file, Guards: guards})
+	return nil
+}
+
+func (c *Communicator) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	if stdout, ok := c.detectionOutput(cmd.Command); ok {
+		if cmd.Stdout != nil {
+			if _, err := io.WriteString(cmd.Stdout, stdout); err != nil {
+				return err
+			}
+		}
+	} else {
+		c.manifest.Commands = append(c.manifest.Commands, cmd.Command)
+	}
+
+	cmd.SetExited(0)
+	return nil
+}
+
+// Returns the output of a command detecting the distribution or architecture of the machine rendered for
+func (c *Communicator) detectionOutput(command string) (string, bool) {
+	switch command {
+	case distro.OsReleaseCommand:
+		return c.osRelease, true
+	case distro.ArchCommand:
+		return "x86_64\n", true
+	default:
+		return "", false
+	}
+}
+
+// Upload Records the uploaded content, which WriteManifest writes into "files/", under its destination path
+func (c *Communicator) Upload(path string, input io.Reader, fi *os.FileInfo) error {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if fi != nil {
+		mode = (*fi).Mode().Perm()
+	}
+
+	file := filepath.Join(filesDir, filepath.Clean("/"+path))
+	c.uploads = append(c.uploads, renderedFile{file: file, content: string(content), mode: mode})
+	c.manifest.Uploads = append(c.manifest.Uploads, Upload{Destination: path, File: file, Mode: fmt.Sprintf("%04o", mode)})
+	return nil
+}
+
+// UploadDir Records the upload of a local directory without copying it
+func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
+	c.manifest.Uploads = append(c.manifest.Uploads, Upload{Destination: dst, Source: src})
+	return nil
+}
+
+func (c *Communicator) Download(path string, output io.Writer) error {
+	return fmt.Errorf("cannot download '%s' in a dry run", path)
+}
+
+func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
+	return fmt.Errorf("cannot download '%s' in a dry run", src)
+}
+
+func (c *Communicator) write(file string, content string, mode os.FileMode) error {
+	path := filepath.Join(c.dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(content), mode)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+// Package render renders a provisioner into a local directory instead of provisioning remote machine, so that the
+// exact scripts and files it would apply can be reviewed, diffed and snapshot-tested without a cloud build.
+//
+// The rendered directory contains
+//
+//   - "scripts/", the shell script of each step, numbered in the order they would run
+//   - "files/", every file that would be uploaded, under its destination path in remote machine
+//   - "manifest.json", which lists the steps, their guards, the uploads and any other commands in order
+//
+// Sensitive values, such as SSL certificate keys, are masked as "<sensitive>" in all of them, and a certificate generated
+// in "self-signed" mode is rendered as a placeholder, so that the rendered directory is the same from run to run.
+//
+// Provisioners embed Config to offer a "dryRunDir" option, and Provision renders any provisioner from Go code
+package render
+
+import (
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"sort"
+	"strings"
+)
+
+// DefaultDryRunDistro The distribution rendered for, unless another one is configured
+const DefaultDryRunDistro string = "ubuntu"
+
+// The /etc/os-release of each distribution that can be rendered for
+var osReleases = map[string]string{
+	"ubuntu": "ID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"22.04\"\n",
+	"debian": "ID=debian\nVERSION_ID=\"12\"\n",
+	"amzn":   "ID=\"amzn\"\nID_LIKE=\"fedora\"\nVERSION_ID=\"2023\"\n",
+	"rocky":  "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n",
+	"fedora": "ID=fedora\nVERSION_ID=39\n",
+	"alpine": "ID=alpine\nVERSION_ID=3.19.0\n",
+}
+
+// Config The dry run options that provisioners embed with `mapstructure:",squash"`
+type Config struct {
+	DryRunDir    string `mapstructure:"dryRunDir" required:"false"`
+	DryRunDistro string `mapstructure:"dryRunDistro" required:"false"`
+}
+
+// ProvisionFunc Provisions a machine through the provided communicator
+type ProvisionFunc func(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error
+
+// Validate Returns an error if the configured dry run distribution is not one that can be rendered for
+func (c *Config) Validate() []error {
+	if _, err := getOsRelease(c.DryRunDistro); err != nil {
+		return []error{fmt.Errorf("dryRunDistro: %s", err)}
+	}
+
+	return nil
+}
+
+// Run Provisions remote machine through the communicator of Packer, unless "dryRunDir" is set, in which case provision
+// is rendered into that directory instead, and the communicator of Packer is not touched at all
+func (c *Config) Run(
+	ctx context.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	provision ProvisionFunc,
+) error {
+	if c.DryRunDir == "" {
+		return provision(ctx, ui, communicator)
+	}
+
+	return Render(ctx, ui, c.DryRunDir, c.DryRunDistro, provision)
+}
+
+// Render Runs provision against a Communicator that writes into dir instead of provisioning a machine.
+//
+// distro: The ID of the distribution to render for, such as "ubuntu" or "rocky"; DefaultDryRunDistro if empty
+func Render(ctx context.Context, ui packersdk.Ui, dir string, distro string, provision ProvisionFunc) error {
+	communicator, err := NewCommunicator(dir, distro)
+	if err != nil {
+		return err
+	}
+
+	ui.Say(fmt.Sprintf("Dry run: rendering into %s instead of provisioning remote machine", dir))
+
+	if err = provision(ctx, ui, communicator); err != nil {
+		return err
+	}
+
+	return communicator.WriteManifest()
+}
+
+// Provision Renders a provisioner, which has been prepared already, into dir. It is the Go API equivalent of
+// "dryRunDir"
+func Provision(ctx context.Context, ui packersdk.Ui, provisioner packersdk.Provisioner, dir string, distro string) error {
+	return Render(ctx, ui, dir, distro, func(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
+		return provisioner.Provision(ctx, ui, communicator, map[string]interface{}{})
+	})
+}
+
+func getOsRelease(distro string) (string, error) {
+	if distro == "" {
+		distro = DefaultDryRunDistro
+	}
+
+	osRelease, ok := osReleases[distro]
+	if !ok {
+		var supported []string
+		for id := range osReleases {
+			supported = append(supported, id)
+		}
+		sort.Strings(supported)
+
+		return "", fmt.Errorf("cannot render for '%s'; supported are %s", distro, strings.Join(supported, ", "))
+	}
+
+	return osRelease, nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+
+	err := Render(context.Background(), packersdk.TestUi(t), dir, "rocky", func(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
+		d, err := distro.Detect(ctx, communicator)
+		if err != nil {
+			return err
+		}
+
+		if err = communicator.Upload("/home/rocky/nginx-ssl.conf", strings.NewReader("server { s3cr3t }"), nil); err != nil {
+			return err
+		}
+		if err = communicator.UploadDir("/home/rocky/dist", "/local/dist", nil); err != nil {
+			return err
+		}
+
+		return shell.Provision(
+			ctx,
+			ui,
+			communicator,
+			[]shell.Step{
+				{Name: "Installing Nginx", Commands: []string{d.InstallCommand("nginx")}},
+				{Name: "Cloning docker-kong", Commands: []string{"echo s3cr3t"}, Creates: "docker-kong"},
+			},
+			"s3cr3t",
+		)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, manifestFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedManifest := Manifest{
+		Distro: "rocky",
+		Steps: []Step{
+			{Name: "Installing Nginx", File: "scripts/01-installing-nginx.sh"},
+			{Name: "Cloning docker-kong", File: "scripts/02-cloning-docker-kong.sh", Guards: []string{"test -e 'docker-kong'"}},
+		},
+		Uploads: []Upload{
+			{Destination: "/home/rocky/nginx-ssl.conf", File: "files/home/rocky/nginx-ssl.conf", Mode: "0644"},
+			{Destination: "/home/rocky/dist", Source: "/local/dist"},
+		},
+	}
+	if !reflect.DeepEqual(expectedManifest, manifest) {
+		t.Errorf("Expected and actual manifests do not match: %+v\n\n%+v", expectedManifest, manifest)
+	}
+
+	script, err := os.ReadFile(filepath.Join(dir, "scripts/01-installing-nginx.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(script), "sudo dnf install -y nginx\n") {
+		t.Errorf("Expected script to install Nginx with dnf, got:\n%s", script)
+	}
+
+	script, err = os.ReadFile(filepath.Join(dir, "scripts/02-cloning-docker-kong.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(script), "s3cr3t") {
+		t.Errorf("Expected sensitive value to be masked, got:\n%s", script)
+	}
+
+	nginxConfig, err := os.ReadFile(filepath.Join(dir, "files/home/rocky/nginx-ssl.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(nginxConfig) != "server { <sensitive> }" {
+		t.Errorf("Expected sensitive value uploaded before being registered to be masked, got:\n%s", nginxConfig)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"default distribution", Config{DryRunDir: "out"}, false},
+		{"supported distribution", Config{DryRunDir: "out", DryRunDistro: "amzn"}, false},
+		{"unsupported distribution", Config{DryRunDir: "out", DryRunDistro: "arch"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.Validate()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
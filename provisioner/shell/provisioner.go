@@ -11,15 +11,29 @@ package shell
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/retry"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/tmp"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Provision Batch executes a list of ordered bash shell commands.
+const dockerPackagesDir string = "docker-packages"
+
+// DockerComposeVersion The version of the Docker Compose plugin installed on distributions that don't package it, such
+// as Amazon Linux
+const DockerComposeVersion string = "v2.29.7"
+
+const dockerCliPluginsDir string = "/usr/local/lib/docker/cli-plugins"
+
+// Provision Batch executes an ordered list of steps, each of which is a named group of bash shell commands.
 //
 // It doesn't reuse Packer's original shell provisioner
 // (https://github.com/hashicorp/packer/blob/main/provisioner/shell/provisioner.go), which is not fully exported for
@@ -27,7 +41,7 @@ import (
 // https://github.com/hashicorp/packer/blob/1e446de977e93b7119ebbaa6f55268bd29240e4f/provisioner/shell/provisioner.go#L73
 // This parameter is unexported because is not capitalized
 //
-// This provisioner works by loading all provided commands into a shell script. We do this because executing commands
+// This provisioner works by loading all commands of a step into a shell script. We do this because executing commands
 // separately in the following way simply doesn't work:
 //
 //	if len(amiConfigCommands) > 0 {
@@ -42,32 +56,150 @@ import (
 // each command is executed in a separate shell, meaning their state is not preserved unless the state is flushed to the
 // hard disk of the remote machine. For example, the regular env variable export like "export JAVA_HOME=..." won't carry
 // over to the next command's execution context. The only way to preserve all in-memory states is to run everything in a
-// one-time script, which is how this function is implemented
-func Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, commands []string) error {
-	scriptFile, err := loadCommandsIntoScript(commands)
-	if err != nil {
-		return err
+// one-time script, which is how each step is executed. In-memory states do not carry over from one step to the next
+//
+// If the communicator is a Renderer, nothing runs in remote machine. The script of each step is handed to the Renderer
+// instead, with sensitive values masked, regardless of whether its guards are satisfied
+//
+// The progress is reported as "Step 3/7: Installing Docker". A step whose Creates or Unless guard is already satisfied
+// in remote machine is skipped, and a failing step aborts the provisioning with an error naming that step
+//
+// Values that must not show up in Packer output, such as passwords or tokens, are passed as sensitive. They are masked
+// as "<sensitive>" in both UI output and Packer logs, and any command containing one of them is excluded from the
+// "set -x" trace of the script. To hide a command entirely, pass the command itself as a sensitive value
+func Provision(
+	ctx context.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	steps []Step,
+	sensitive ...string,
+) error {
+	packersdk.LogSecretFilter.Set(sensitive...)
+	ui = &redactingUi{ui}
+	renderer, rendering := communicator.(Renderer)
+
+	for i, step := range steps {
+		progress := fmt.Sprintf("Step %d/%d: %s", i+1, len(steps), step.Name)
+		ui.Say(progress)
+
+		if rendering {
+			if err := renderStep(renderer, step, sensitive); err != nil {
+				return fmt.Errorf("%s: %s", progress, err)
+			}
+			continue
+		}
+
+		satisfied, err := step.isSatisfied(ctx, communicator)
+		if err != nil {
+			return fmt.Errorf("%s: error checking whether step is already satisfied: %s", progress, err)
+		}
+		if satisfied {
+			ui.Say(fmt.Sprintf("%s: already satisfied, skipping", progress))
+			continue
+		}
+
+		if err := provisionStep(ctx, ui, communicator, step, sensitive); err != nil {
+			return fmt.Errorf("%s: %s", progress, err)
+		}
 	}
-	defer os.Remove(scriptFile.Name())
 
-	ui.Say(fmt.Sprintf("Provisioning with %s", commands))
+	return nil
+}
 
-	return executeScript(ctx, ui, communicator, scriptFile)
+// StepInstallingSudoLessDocker returns a step that installs sudo-free Docker in remote machine
+func StepInstallingSudoLessDocker(d *distro.Distro) Step {
+	return Step{Name: "Installing Docker", Commands: CommandsInstallingSudoLessDocker(d)}
 }
 
-// CommandsInstallingSudoLessDocker returns an ordered list of commands that installs sudo-free Docker in remote machine
-func CommandsInstallingSudoLessDocker() []string {
-	return []string{
-		"sudo apt update && sudo apt upgrade -y",
-		"sudo apt install git software-properties-common -y",
+// CommandsInstallingSudoLessDocker returns an ordered list of commands that installs sudo-free Docker, together with
+// git and the Docker Compose plugin, in remote machine of the provided distribution. Amazon Linux packages Docker
+// without the Compose plugin, which is downloaded from the Docker Compose releases on GitHub instead
+func CommandsInstallingSudoLessDocker(d *distro.Distro) []string {
+	var commands []string
+
+	switch {
+	case d.PackageManager == distro.Apt:
+		commands = []string{
+			d.UpgradeCommand(),
+			d.InstallCommand("git", "software-properties-common"),
 
-		"git clone https://github.com/QubitPi/docker-install.git && cd docker-install && sh install.sh",
+			"git clone https://github.com/QubitPi/docker-install.git && cd docker-install && sh install.sh",
+		}
+	case d.ID == "amzn":
+		commands = []string{
+			d.UpgradeCommand(),
+			d.InstallCommand("git", "docker"),
+			fmt.Sprintf("sudo mkdir -p %s", dockerCliPluginsDir),
+			fmt.Sprintf(
+				"sudo curl -fsSL -o %s/docker-compose https://github.com/docker/compose/releases/download/%s/docker-compose-linux-$(uname -m)",
+				dockerCliPluginsDir,
+				DockerComposeVersion,
+			),
+			fmt.Sprintf("sudo chmod +x %s/docker-compose", dockerCliPluginsDir),
+			d.ServiceEnableCommand("docker"),
+		}
+	case d.PackageManager == distro.Apk:
+		commands = []string{
+			d.UpgradeCommand(),
+			d.InstallCommand("git", "docker", "docker-cli-compose"),
+			d.ServiceEnableCommand("docker"),
+		}
+	default:
+		commands = []string{
+			d.UpgradeCommand(),
+			d.InstallCommand("git", "yum-utils"),
+			"sudo yum-config-manager --add-repo https://download.docker.com/linux/centos/docker-ce.repo",
+			d.InstallCommand("docker-ce", "docker-ce-cli", "containerd.io", "docker-compose-plugin"),
+			d.ServiceEnableCommand("docker"),
+		}
+	}
+
+	return append(
+		commands,
 		"sudo usermod -aG docker ${USER}",
 		"sudo chmod o+rw /var/run/docker.sock",
+	)
+}
+
+// StepInstallingDocker Returns a step that installs sudo-free Docker in remote machine. If packagesSource, a local
+// directory of Docker package files, is given, the package files are uploaded to "<homeDir>/docker-packages" first and
+// Docker is installed from them without access to the internet. Otherwise, Docker is installed over the internet
+func StepInstallingDocker(
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	d *distro.Distro,
+	homeDir string,
+	packagesSource string,
+) (Step, error) {
+	if packagesSource == "" {
+		return StepInstallingSudoLessDocker(d), nil
+	}
+
+	packagesDir := filepath.Join(homeDir, dockerPackagesDir)
+	if err := file.ProvisionDirContents(interCtx, ui, communicator, packagesSource, packagesDir); err != nil {
+		return Step{}, err
+	}
+
+	return StepInstallingSudoLessDockerFromPackages(d, packagesDir), nil
+}
+
+// StepInstallingSudoLessDockerFromPackages returns a step that installs sudo-free Docker from the Docker package files,
+// such as those downloaded from https://download.docker.com/linux/, in a directory of remote machine. Unlike
+// StepInstallingSudoLessDocker, it needs no access to the internet
+func StepInstallingSudoLessDockerFromPackages(d *distro.Distro, packagesDir string) Step {
+	return Step{
+		Name: "Installing Docker from packages",
+		Commands: []string{
+			d.InstallLocalCommand(packagesDir),
+			d.ServiceEnableCommand("docker"),
+			"sudo usermod -aG docker ${USER}",
+			"sudo chmod o+rw /var/run/docker.sock",
+		},
 	}
 }
 
-func loadCommandsIntoScript(commands []string) (*os.File, error) {
+func loadCommandsIntoScript(commands []string, sensitive []string) (*os.File, error) {
 	scriptFile, err := tmp.File("packer-shell")
 	if err != nil {
 		return nil, fmt.Errorf("error while trying to load commands into a shell script: %s", err)
@@ -79,6 +211,9 @@ func loadCommandsIntoScript(commands []string) (*os.File, error) {
 	writer.WriteString("set -e\n")
 	writer.WriteString("\n")
 	for _, command := range commands {
+		if isSensitive(command, sensitive) {
+			command = fmt.Sprintf("{ set +x; } 2>/dev/null\n%s\nset -x", command)
+		}
 		if _, err := writer.WriteString(command + "\n"); err != nil {
 			return nil, fmt.Errorf("error flushing command '%s' into a shell script: %s", command, err)
 		}
@@ -92,6 +227,45 @@ func loadCommandsIntoScript(commands []string) (*os.File, error) {
 	return scriptFile, err
 }
 
+func isSensitive(command string, sensitive []string) bool {
+	for _, value := range sensitive {
+		if value != "" && strings.Contains(command, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func provisionStep(
+	ctx context.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	step Step,
+	sensitive []string,
+) error {
+	scriptFile, err := loadCommandsIntoScript(step.scriptCommands(), sensitive)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(scriptFile.Name())
+
+	ui.Say(fmt.Sprintf("Provisioning with %s", step.Commands))
+
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	err = executeScript(ctx, ui, communicator, scriptFile)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", step.Timeout)
+	}
+
+	return err
+}
+
 func executeScript(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, scriptFile *os.File) error {
 	f, err := os.Open(scriptFile.Name())
 	if err != nil {
@@ -126,5 +300,9 @@ func executeScript(ctx context.Context, ui packersdk.Ui, communicator packersdk.
 		return err
 	}
 
+	if cmd.ExitStatus() != 0 {
+		return fmt.Errorf("script exited with non-zero exit status %d", cmd.ExitStatus())
+	}
+
 	return nil
 }
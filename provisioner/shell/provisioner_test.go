@@ -4,15 +4,41 @@
 package shell
 
 import (
+	"bytes"
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+// A fake machine on which scripts never exit, for example because a command waits for a service that never comes up
+type hangingCommunicator struct {
+	*communicatortest.Communicator
+}
+
+func (c *hangingCommunicator) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	if strings.HasPrefix(cmd.Command, "chmod +x ") {
+		return nil
+	}
+
+	return c.Communicator.Start(ctx, cmd)
+}
+
+func newBufferedUi() (*packersdk.BasicUi, *bytes.Buffer) {
+	var output bytes.Buffer
+	return &packersdk.BasicUi{Reader: strings.NewReader(""), Writer: &output, ErrorWriter: &output}, &output
+}
+
 func Test_loadCommandsIntoScript(t *testing.T) {
 	actualScript, err := loadCommandsIntoScript([]string{
 		"sudo apt update && sudo apt upgrade -y",
 		"sudo apt install software-properties-common -y",
-	})
+	}, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -35,3 +61,203 @@ sudo apt install software-properties-common -y
 		t.Errorf("Expected and actual scripts do not match: %s\n\n%s", expectedScript, string(b))
 	}
 }
+
+func Test_loadCommandsIntoScriptWithSensitiveValues(t *testing.T) {
+	actualScript, err := loadCommandsIntoScript([]string{
+		"sudo apt install -y curl",
+		"curl -u admin:s3cr3t https://nexus.mycompany.com",
+	}, []string{"s3cr3t"})
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.Remove(actualScript.Name())
+
+	b, err := os.ReadFile(actualScript.Name())
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectedScript := `#!/bin/bash
+set -x
+set -e
+
+sudo apt install -y curl
+{ set +x; } 2>/dev/null
+curl -u admin:s3cr3t https://nexus.mycompany.com
+set -x
+`
+
+	if string(b) != expectedScript {
+		t.Errorf("Expected and actual scripts do not match: %s\n\n%s", expectedScript, string(b))
+	}
+}
+
+func TestCommandsInstallingSudoLessDocker(t *testing.T) {
+	data := []struct {
+		name             string
+		distro           *distro.Distro
+		expectedCommands []string
+	}{
+		{
+			"Ubuntu",
+			&distro.Distro{ID: "ubuntu", PackageManager: distro.Apt},
+			[]string{
+				"sudo apt update && sudo apt upgrade -y",
+				"sudo apt install -y git software-properties-common",
+				"git clone https://github.com/QubitPi/docker-install.git && cd docker-install && sh install.sh",
+				"sudo usermod -aG docker ${USER}",
+				"sudo chmod o+rw /var/run/docker.sock",
+			},
+		},
+		{
+			"Amazon Linux",
+			&distro.Distro{ID: "amzn", PackageManager: distro.Dnf},
+			[]string{
+				"sudo dnf update -y",
+				"sudo dnf install -y git docker",
+				"sudo mkdir -p /usr/local/lib/docker/cli-plugins",
+				"sudo curl -fsSL -o /usr/local/lib/docker/cli-plugins/docker-compose https://github.com/docker/compose/releases/download/v2.29.7/docker-compose-linux-$(uname -m)",
+				"sudo chmod +x /usr/local/lib/docker/cli-plugins/docker-compose",
+				"sudo systemctl enable --now docker",
+				"sudo usermod -aG docker ${USER}",
+				"sudo chmod o+rw /var/run/docker.sock",
+			},
+		},
+		{
+			"Amazon Linux 2",
+			&distro.Distro{ID: "amzn", VersionID: "2", PackageManager: distro.Yum},
+			[]string{
+				"sudo yum update -y",
+				"sudo yum install -y git docker",
+				"sudo mkdir -p /usr/local/lib/docker/cli-plugins",
+				"sudo curl -fsSL -o /usr/local/lib/docker/cli-plugins/docker-compose https://github.com/docker/compose/releases/download/v2.29.7/docker-compose-linux-$(uname -m)",
+				"sudo chmod +x /usr/local/lib/docker/cli-plugins/docker-compose",
+				"sudo systemctl enable --now docker",
+				"sudo usermod -aG docker ${USER}",
+				"sudo chmod o+rw /var/run/docker.sock",
+			},
+		},
+		{
+			"Rocky Linux",
+			&distro.Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, PackageManager: distro.Dnf},
+			[]string{
+				"sudo dnf update -y",
+				"sudo dnf install -y git yum-utils",
+				"sudo yum-config-manager --add-repo https://download.docker.com/linux/centos/docker-ce.repo",
+				"sudo dnf install -y docker-ce docker-ce-cli containerd.io docker-compose-plugin",
+				"sudo systemctl enable --now docker",
+				"sudo usermod -aG docker ${USER}",
+				"sudo chmod o+rw /var/run/docker.sock",
+			},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			actualCommands := CommandsInstallingSudoLessDocker(d.distro)
+			if !reflect.DeepEqual(d.expectedCommands, actualCommands) {
+				t.Errorf("Expected and actual commands do not match: %s\n\n%s", d.expectedCommands, actualCommands)
+			}
+		})
+	}
+}
+
+func TestProvision(t *testing.T) {
+	ui, output := newBufferedUi()
+	communicator := communicatortest.New()
+
+	err := Provision(context.Background(), ui, communicator, []Step{
+		{Name: "Installing curl", Commands: []string{"sudo apt install -y curl"}},
+		{Name: "Installing Docker", Commands: []string{"sh install.sh"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, progress := range []string{"Step 1/2: Installing curl\n", "Step 2/2: Installing Docker\n"} {
+		if !strings.Contains(output.String(), progress) {
+			t.Errorf("Expected progress '%s', got:\n%s", progress, output.String())
+		}
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 2 || !strings.Contains(scripts[0], "sudo apt install -y curl\n") || !strings.Contains(scripts[1], "sh install.sh\n") {
+		t.Errorf("Expected both steps to run in order, got: %s", scripts)
+	}
+}
+
+func TestProvisionSkipsSatisfiedSteps(t *testing.T) {
+	data := []struct {
+		name string
+		step Step
+	}{
+		{"creates", Step{Name: "Cloning docker-kong", Commands: []string{"git clone docker-kong"}, Creates: "/home/ubuntu/docker-kong"}},
+		{"unless", Step{Name: "Creating volume", Commands: []string{"docker volume create data"}, Unless: "docker volume inspect data"}},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			ui, output := newBufferedUi()
+			communicator := communicatortest.New()
+			if err := communicator.Upload("/home/ubuntu/docker-kong/README.md", strings.NewReader("docker-kong"), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Provision(context.Background(), ui, communicator, []Step{d.step}); err != nil {
+				t.Fatal(err)
+			}
+
+			if scripts := communicator.Scripts(); len(scripts) != 0 {
+				t.Errorf("Expected satisfied step to be skipped, got: %s", scripts)
+			}
+			if !strings.Contains(output.String(), "Step 1/1: "+d.step.Name+": already satisfied, skipping\n") {
+				t.Errorf("Expected skipped step to be reported, got:\n%s", output.String())
+			}
+		})
+	}
+
+	communicator := communicatortest.New().On("docker volume inspect data", 1, "")
+	step := Step{Name: "Creating volume", Commands: []string{"docker volume create data"}, Unless: "docker volume inspect data"}
+	if err := Provision(context.Background(), packersdk.TestUi(t), communicator, []Step{step}); err != nil {
+		t.Fatal(err)
+	}
+	if scripts := communicator.Scripts(); len(scripts) != 1 {
+		t.Errorf("Expected unsatisfied step to run, got: %s", scripts)
+	}
+}
+
+func TestProvisionFailingStep(t *testing.T) {
+	communicator := communicatortest.New().On("sh install.sh", 3, "")
+
+	err := Provision(context.Background(), packersdk.TestUi(t), communicator, []Step{
+		{Name: "Installing Docker", Commands: []string{"sh install.sh"}},
+		{Name: "Starting Docker", Commands: []string{"sudo systemctl start docker"}},
+	})
+	if err == nil {
+		t.Fatal("Expected failing step to fail provisioning")
+	}
+	if !strings.HasPrefix(err.Error(), "Step 1/2: Installing Docker: ") || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Expected error to name the failing step, got: %s", err)
+	}
+	if scripts := communicator.Scripts(); len(scripts) != 1 {
+		t.Errorf("Expected no step to run after the failing one, got: %s", scripts)
+	}
+}
+
+func TestProvisionStepTimeout(t *testing.T) {
+	communicator := &hangingCommunicator{communicatortest.New()}
+
+	start := time.Now()
+	err := Provision(context.Background(), packersdk.TestUi(t), communicator, []Step{
+		{Name: "Waiting for Nexus", Commands: []string{"until curl -fs localhost:8081; do sleep 5; done"}, Timeout: 100 * time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("Expected hanging step to time out")
+	}
+	if err.Error() != "Step 1/1: Waiting for Nexus: timed out after 100ms" {
+		t.Errorf("Expected timeout to name the step, got: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected step to be aborted after its timeout, took %s", elapsed)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package shell
+
+import (
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// redactingUi A packersdk.Ui that masks all values registered with packersdk.LogSecretFilter before they reach the
+// wrapped Ui, including the output streamed back from remote commands
+type redactingUi struct {
+	packersdk.Ui
+}
+
+func (u *redactingUi) Say(message string) {
+	u.Ui.Say(packersdk.LogSecretFilter.FilterString(message))
+}
+
+func (u *redactingUi) Message(message string) {
+	u.Ui.Message(packersdk.LogSecretFilter.FilterString(message))
+}
+
+func (u *redactingUi) Error(message string) {
+	u.Ui.Error(packersdk.LogSecretFilter.FilterString(message))
+}
@@ -0,0 +1,32 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package shell
+
+import (
+	"context"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"strings"
+	"testing"
+)
+
+func TestProvisionMasksSensitiveValues(t *testing.T) {
+	ui, output := newBufferedUi()
+	communicator := communicatortest.New().On("login", 0, "logged in with p4ssw0rd\n")
+
+	err := Provision(context.Background(), ui, communicator, []Step{
+		{Name: "Logging in", Commands: []string{"login --password p4ssw0rd"}},
+	}, "p4ssw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(output.String(), "p4ssw0rd") {
+		t.Errorf("Expected sensitive value to be masked, got:\n%s", output.String())
+	}
+	for _, message := range []string{"Provisioning with [login --password <sensitive>]\n", "logged in with <sensitive>\n"} {
+		if !strings.Contains(output.String(), message) {
+			t.Errorf("Expected '%s' in output, got:\n%s", message, output.String())
+		}
+	}
+}
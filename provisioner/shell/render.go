@@ -0,0 +1,31 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package shell
+
+import (
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"os"
+)
+
+// Renderer is implemented by communicators that record the steps of a provisioner instead of running them in remote
+// machine, such as the one of the render package
+type Renderer interface {
+	// RenderStep Records the script that runs a step, together with the commands that would check its guards
+	RenderStep(name string, guards []string, script string) error
+}
+
+func renderStep(renderer Renderer, step Step, sensitive []string) error {
+	scriptFile, err := loadCommandsIntoScript(step.scriptCommands(), sensitive)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(scriptFile.Name())
+
+	script, err := os.ReadFile(scriptFile.Name())
+	if err != nil {
+		return err
+	}
+
+	return renderer.RenderStep(step.Name, step.guards(), packersdk.LogSecretFilter.FilterString(string(script)))
+}
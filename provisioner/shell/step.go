@@ -0,0 +1,92 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Step A named group of ordered bash shell commands that Provision runs in one script
+type Step struct {
+	// Name Describes the step in UI output and errors, for example "Installing Docker"
+	Name string
+	// Commands The ordered list of commands run by the step
+	Commands []string
+	// Creates A path in remote machine that the step creates. The step is skipped if the path already exists
+	Creates string
+	// Unless A command run in remote machine before the step. The step is skipped if the command exits with 0
+	Unless string
+	// Env Environment variables exported to all commands of the step
+	Env map[string]string
+	// Timeout The maximum duration of the step; no limit if 0
+	Timeout time.Duration
+}
+
+// Returns the commands of the script that runs the step, which export the environment variables of the step first
+func (s Step) scriptCommands() []string {
+	var commands []string
+	for _, name := range SortedKeys(s.Env) {
+		commands = append(commands, fmt.Sprintf("export %s=%s", name, Quote(s.Env[name])))
+	}
+
+	return append(commands, s.Commands...)
+}
+
+// Returns the commands that check the Creates and Unless guards of the step. The step is skipped if any of them exits
+// with 0
+func (s Step) guards() []string {
+	var guards []string
+	if s.Creates != "" {
+		guards = append(guards, fmt.Sprintf("test -e %s", Quote(s.Creates)))
+	}
+	if s.Unless != "" {
+		guards = append(guards, s.Unless)
+	}
+
+	return guards
+}
+
+// Returns whether the Creates or Unless guard of the step is satisfied in remote machine
+func (s Step) isSatisfied(ctx context.Context, communicator packersdk.Communicator) (bool, error) {
+	for _, guard := range s.guards() {
+		satisfied, err := succeeds(ctx, communicator, guard)
+		if err != nil || satisfied {
+			return satisfied, err
+		}
+	}
+
+	return false, nil
+}
+
+func succeeds(ctx context.Context, communicator packersdk.Communicator, command string) (bool, error) {
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: io.Discard, Stderr: io.Discard}
+	if err := communicator.Start(ctx, cmd); err != nil {
+		return false, err
+	}
+
+	return cmd.Wait() == 0, nil
+}
+
+// Quote Single-quotes a value for bash and other POSIX shells, so that it is passed as a single word without expansion
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// SortedKeys Returns the keys of a map in ascending order, so that commands and files generated from the map, such as
+// exported environment variables, are the same on every run
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
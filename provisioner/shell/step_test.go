@@ -0,0 +1,32 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStep_scriptCommands(t *testing.T) {
+	step := Step{
+		Name:     "Installing JDK 17",
+		Commands: []string{"sudo apt install openjdk-17-jdk -y"},
+		Env: map[string]string{
+			"JAVA_HOME":       "/usr/lib/jvm/java-17-openjdk-amd64",
+			"DEBIAN_FRONTEND": "noninteractive",
+			"GREETING":        "it's me",
+		},
+	}
+
+	expectedCommands := []string{
+		"export DEBIAN_FRONTEND='noninteractive'",
+		`export GREETING='it'"'"'s me'`,
+		"export JAVA_HOME='/usr/lib/jvm/java-17-openjdk-amd64'",
+		"sudo apt install openjdk-17-jdk -y",
+	}
+
+	if actualCommands := step.scriptCommands(); !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("Expected and actual commands do not match: %s\n\n%s", expectedCommands, actualCommands)
+	}
+}
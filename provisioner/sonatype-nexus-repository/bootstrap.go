@@ -0,0 +1,493 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+// DefaultAdminPasswordFile The file in the image that the rotated admin password is stored in, readable by root only,
+// unless another one is configured
+const DefaultAdminPasswordFile string = "/etc/nexus/admin.password"
+
+// DefaultBlobStore The blob store that Nexus creates on first start, which repositories use unless configured otherwise
+const DefaultBlobStore string = "default"
+
+const (
+	RepositoryFormatMaven  string = "maven"
+	RepositoryFormatNpm    string = "npm"
+	RepositoryFormatDocker string = "docker"
+)
+
+const (
+	RepositoryTypeHosted string = "hosted"
+	RepositoryTypeProxy  string = "proxy"
+	RepositoryTypeGroup  string = "group"
+)
+
+const bootstrapScriptFilename string = "nexus-bootstrap"
+const stagedAdminPasswordFilename string = "nexus-admin.password"
+
+// The cache durations of proxy repositories, in minutes, which are the defaults of the Nexus UI
+const proxyCacheMinutes int = 1440
+
+var nexusNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// BlobStore A file blob store
+type BlobStore struct {
+	// Name The name of the blob store
+	Name string `mapstructure:"name" required:"true"`
+	// Path The directory of the blob store, either absolute or relative to the blob store directory in nexus-data.
+	// Default to the name
+	Path string `mapstructure:"path" required:"false"`
+}
+
+// Repository A hosted, proxy or group repository of Maven, npm or Docker artifacts
+type Repository struct {
+	// Name The name of the repository
+	Name string `mapstructure:"name" required:"true"`
+	// Format "maven", "npm" or "docker"
+	Format string `mapstructure:"format" required:"true"`
+	// Type "hosted", "proxy" or "group"
+	Type string `mapstructure:"type" required:"true"`
+	// BlobStore The blob store that the repository stores its artifacts in. Default to "default"
+	BlobStore string `mapstructure:"blobStore" required:"false"`
+	// RemoteUrl The URL of the remote repository that a proxy repository proxies; required for proxy repositories
+	RemoteUrl string `mapstructure:"remoteUrl" required:"false"`
+	// Members The names of the repositories that a group repository groups, in order; required for group repositories
+	Members []string `mapstructure:"members" required:"false"`
+	// VersionPolicy "RELEASE", "SNAPSHOT" or "MIXED", for hosted and proxy Maven repositories. Default to "RELEASE"
+	VersionPolicy string `mapstructure:"versionPolicy" required:"false"`
+	// WritePolicy "allow", "allow_once" or "deny", for hosted repositories. Default to "allow_once"
+	WritePolicy string `mapstructure:"writePolicy" required:"false"`
+}
+
+// Role A role granting privileges and other roles
+type Role struct {
+	// Id The ID of the role
+	Id string `mapstructure:"id" required:"true"`
+	// Name The name of the role. Default to the ID
+	Name string `mapstructure:"name" required:"false"`
+	// Description The description of the role
+	Description string `mapstructure:"description" required:"false"`
+	// Privileges The privileges that the role grants, such as "nx-repository-view-maven2-maven-releases-*"
+	Privileges []string `mapstructure:"privileges" required:"false"`
+	// Roles The IDs of the roles that the role contains
+	Roles []string `mapstructure:"roles" required:"false"`
+}
+
+// User A local user
+type User struct {
+	// UserId The ID that the user logs in with
+	UserId string `mapstructure:"userId" required:"true"`
+	// FirstName The first name of the user
+	FirstName string `mapstructure:"firstName" required:"true"`
+	// LastName The last name of the user
+	LastName string `mapstructure:"lastName" required:"true"`
+	// Email The email address of the user
+	Email string `mapstructure:"email" required:"true"`
+	// Password The password of the user, which is kept out of Packer output
+	Password string `mapstructure:"password" required:"true"`
+	// Roles The IDs of the roles of the user
+	Roles []string `mapstructure:"roles" required:"true"`
+}
+
+// A request to the REST API of Nexus
+type apiCall struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+func (c *Config) adminPasswordFile() string {
+	if c.AdminPasswordFile == "" {
+		return DefaultAdminPasswordFile
+	}
+
+	return c.AdminPasswordFile
+}
+
+// Returns the values that are masked in Packer output, which are the admin password and the passwords of the users
+func (c *Config) bootstrapSensitiveValues() []string {
+	var values []string
+	if c.AdminPassword != "" {
+		values = append(values, c.AdminPassword)
+	}
+	for _, user := range c.Users {
+		if user.Password != "" {
+			values = append(values, user.Password)
+		}
+	}
+
+	return values
+}
+
+func (c *Config) validateBootstrap() []error {
+	var errs []error
+
+	if !filepath.IsAbs(c.adminPasswordFile()) {
+		errs = append(errs, fmt.Errorf("adminPasswordFile: '%s' is not an absolute path", c.AdminPasswordFile))
+	}
+	if c.AdminPassword != "" && len(c.AdminPassword) < 8 {
+		errs = append(errs, fmt.Errorf("adminPassword: must be at least 8 characters long"))
+	}
+
+	blobStores := map[string]bool{DefaultBlobStore: true}
+	for i, b := range c.BlobStores {
+		field := fmt.Sprintf("blobStores[%d]", i)
+		errs = append(errs, checkRequired(field, &b)...)
+		errs = append(errs, checkName(field+".name", b.Name)...)
+		if blobStores[b.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: blob store '%s' is defined more than once", field, b.Name))
+		}
+		blobStores[b.Name] = true
+	}
+
+	repositories := make(map[string]bool)
+	for i, r := range c.Repositories {
+		field := fmt.Sprintf("repositories[%d]", i)
+		errs = append(errs, checkRequired(field, &r)...)
+		errs = append(errs, checkName(field+".name", r.Name)...)
+		if repositories[r.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: repository '%s' is defined more than once", field, r.Name))
+		}
+		repositories[r.Name] = true
+
+		if !blobStores[r.blobStore()] {
+			errs = append(errs, fmt.Errorf("%s.blobStore: '%s' is neither '%s' nor one of blobStores", field, r.BlobStore, DefaultBlobStore))
+		}
+		errs = append(errs, r.validate(field)...)
+	}
+
+	for i, role := range c.Roles {
+		field := fmt.Sprintf("roles[%d]", i)
+		errs = append(errs, checkRequired(field, &role)...)
+		errs = append(errs, checkName(field+".id", role.Id)...)
+	}
+
+	for i, user := range c.Users {
+		field := fmt.Sprintf("users[%d]", i)
+		errs = append(errs, checkRequired(field, &user)...)
+		errs = append(errs, checkName(field+".userId", user.UserId)...)
+	}
+
+	return errs
+}
+
+func (r *Repository) blobStore() string {
+	if r.BlobStore == "" {
+		return DefaultBlobStore
+	}
+
+	return r.BlobStore
+}
+
+func (r *Repository) versionPolicy() string {
+	if r.VersionPolicy == "" {
+		return "RELEASE"
+	}
+
+	return r.VersionPolicy
+}
+
+func (r *Repository) writePolicy() string {
+	if r.WritePolicy == "" {
+		return "allow_once"
+	}
+
+	return r.WritePolicy
+}
+
+func (r *Repository) validate(field string) []error {
+	var errs []error
+
+	switch r.Format {
+	case "", RepositoryFormatMaven, RepositoryFormatNpm, RepositoryFormatDocker:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%s.format: '%s' is not one of '%s', '%s' and '%s'",
+			field,
+			r.Format,
+			RepositoryFormatMaven,
+			RepositoryFormatNpm,
+			RepositoryFormatDocker,
+		))
+	}
+
+	switch r.Type {
+	case "", RepositoryTypeHosted, RepositoryTypeProxy, RepositoryTypeGroup:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%s.type: '%s' is not one of '%s', '%s' and '%s'",
+			field,
+			r.Type,
+			RepositoryTypeHosted,
+			RepositoryTypeProxy,
+			RepositoryTypeGroup,
+		))
+	}
+
+	if r.Type == RepositoryTypeProxy {
+		if remote, err := url.Parse(r.RemoteUrl); err != nil || (remote.Scheme != "http" && remote.Scheme != "https") || remote.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.remoteUrl: '%s' is not an HTTP(S) URL", field, r.RemoteUrl))
+		}
+	} else if r.RemoteUrl != "" {
+		errs = append(errs, fmt.Errorf("%s.remoteUrl: only proxy repositories have a remote URL", field))
+	}
+
+	if r.Type == RepositoryTypeGroup {
+		if len(r.Members) == 0 {
+			errs = append(errs, fmt.Errorf("%s.members: a group repository needs at least one member", field))
+		}
+	} else if len(r.Members) > 0 {
+		errs = append(errs, fmt.Errorf("%s.members: only group repositories have members", field))
+	}
+
+	if r.VersionPolicy != "" && (r.Format != RepositoryFormatMaven || r.Type == RepositoryTypeGroup) {
+		errs = append(errs, fmt.Errorf("%s.versionPolicy: only hosted and proxy Maven repositories have a version policy", field))
+	}
+	switch r.versionPolicy() {
+	case "RELEASE", "SNAPSHOT", "MIXED":
+	default:
+		errs = append(errs, fmt.Errorf("%s.versionPolicy: '%s' is not one of 'RELEASE', 'SNAPSHOT' and 'MIXED'", field, r.VersionPolicy))
+	}
+
+	if r.WritePolicy != "" && r.Type != RepositoryTypeHosted {
+		errs = append(errs, fmt.Errorf("%s.writePolicy: only hosted repositories have a write policy", field))
+	}
+	switch r.writePolicy() {
+	case "allow", "allow_once", "deny":
+	default:
+		errs = append(errs, fmt.Errorf("%s.writePolicy: '%s' is not one of 'allow', 'allow_once' and 'deny'", field, r.WritePolicy))
+	}
+
+	return errs
+}
+
+// Returns the request body that creates the repository, which differs by format and type. A Docker repository opens an
+// HTTP connector on httpPort unless it is 0
+func (r *Repository) body(httpPort int) map[string]interface{} {
+	storage := map[string]interface{}{"blobStoreName": r.blobStore(), "strictContentTypeValidation": true}
+	body := map[string]interface{}{"name": r.Name, "online": true, "storage": storage}
+
+	switch r.Type {
+	case RepositoryTypeHosted:
+		storage["writePolicy"] = r.writePolicy()
+	case RepositoryTypeProxy:
+		body["proxy"] = map[string]interface{}{
+			"remoteUrl":      r.RemoteUrl,
+			"contentMaxAge":  proxyCacheMinutes,
+			"metadataMaxAge": proxyCacheMinutes,
+		}
+		body["negativeCache"] = map[string]interface{}{"enabled": true, "timeToLive": proxyCacheMinutes}
+		body["httpClient"] = map[string]interface{}{"blocked": false, "autoBlock": true}
+	case RepositoryTypeGroup:
+		body["group"] = map[string]interface{}{"memberNames": r.Members}
+	}
+
+	switch {
+	case r.Format == RepositoryFormatMaven && r.Type != RepositoryTypeGroup:
+		body["maven"] = map[string]interface{}{"versionPolicy": r.versionPolicy(), "layoutPolicy": "STRICT"}
+	case r.Format == RepositoryFormatDocker:
+		docker := map[string]interface{}{"v1Enabled": false, "forceBasicAuth": true}
+		if httpPort != 0 {
+			docker["httpPort"] = httpPort
+		}
+		body["docker"] = docker
+		if r.Type == RepositoryTypeProxy {
+			body["dockerProxy"] = map[string]interface{}{"indexType": getDockerIndexType(r.RemoteUrl)}
+		}
+	}
+
+	return body
+}
+
+// Returns the index type of a Docker proxy repository, which is "HUB" for Docker Hub and "REGISTRY" otherwise
+func getDockerIndexType(remoteUrl string) string {
+	if remote, err := url.Parse(remoteUrl); err == nil && remote.Host == "registry-1.docker.io" {
+		return "HUB"
+	}
+
+	return "REGISTRY"
+}
+
+// Returns the requests that apply the blob stores, repositories, roles, users and anonymous access, in this order, so
+// that everything exists before it is referenced. Group repositories come after all others for the same reason
+func getApiCalls(config Config) []apiCall {
+	var calls []apiCall
+
+	for _, b := range config.BlobStores {
+		path := b.Path
+		if path == "" {
+			path = b.Name
+		}
+		calls = append(calls, newApiCall("POST", "/v1/blobstores/file", map[string]interface{}{"name": b.Name, "path": path}))
+	}
+
+	for _, group := range []bool{false, true} {
+		for _, r := range config.Repositories {
+			if (r.Type == RepositoryTypeGroup) == group {
+				calls = append(calls, newApiCall("POST", fmt.Sprintf("/v1/repositories/%s/%s", r.Format, r.Type), r.body(config.dockerConnectorPort(r.Name))))
+			}
+		}
+	}
+
+	for _, role := range config.Roles {
+		name := role.Name
+		if name == "" {
+			name = role.Id
+		}
+		calls = append(calls, newApiCall("POST", "/v1/security/roles", map[string]interface{}{
+			"id":          role.Id,
+			"name":        name,
+			"description": role.Description,
+			"privileges":  nonNil(role.Privileges),
+			"roles":       nonNil(role.Roles),
+		}))
+	}
+
+	for _, user := range config.Users {
+		calls = append(calls, newApiCall("POST", "/v1/security/users", map[string]interface{}{
+			"userId":       user.UserId,
+			"firstName":    user.FirstName,
+			"lastName":     user.LastName,
+			"emailAddress": user.Email,
+			"password":     user.Password,
+			"status":       "active",
+			"roles":        nonNil(user.Roles),
+		}))
+	}
+
+	return append(calls, newApiCall("PUT", "/v1/security/anonymous", map[string]interface{}{
+		"enabled":   config.AnonymousAccess,
+		"userId":    "anonymous",
+		"realmName": "NexusAuthorizingRealm",
+	}))
+}
+
+func newApiCall(method string, path string, body map[string]interface{}) apiCall {
+	// Marshalling maps of strings, numbers, booleans and string slices never fails
+	content, _ := json.Marshal(body)
+
+	return apiCall{method, path, shell.Quote(string(content))}
+}
+
+// Returns the POSIX shell script that bootstraps Nexus through its REST API. It rotates the admin password, which Nexus
+// generates into /nexus-data/admin.password on first start, to the configured one or else to a random one, writes it
+// into the file given as the first argument and then applies the blob stores, repositories, roles, users and anonymous
+// access as admin.
+//
+// Nexus is reached at NEXUS_URL, which defaults to the port published on the loopback interface, and its initial admin
+// password is read from NEXUS_INITIAL_PASSWORD, if set, or else from the Nexus container
+func getBootstrapScript(config Config) string {
+	var scriptConfigs = struct {
+		Port          string
+		ContainerName string
+		AdminPassword string
+		Calls         []apiCall
+	}{PORT, ContainerName, "", getApiCalls(config)}
+	if config.AdminPassword != "" {
+		scriptConfigs.AdminPassword = shell.Quote(config.AdminPassword)
+	}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("Nexus Bootstrap Script").Parse(`#!/bin/sh
+# Bootstraps Sonatype Nexus through its REST API
+set -e
+
+url="${NEXUS_URL:-http://127.0.0.1:{{.Port}}}"
+password_file="$1"
+initial_password="${NEXUS_INITIAL_PASSWORD:-$(docker exec {{.ContainerName}} cat /nexus-data/admin.password)}"
+
+response=$(mktemp)
+trap 'rm -f "$response"' EXIT
+
+# Sends a request to the REST API as admin and fails unless it succeeds.
+# Arguments: admin password, method, path, content type, body
+call() {
+    status=$(curl -sS -o "$response" -w '%{http_code}' -u "admin:$1" -X "$2" -H "Content-Type: $4" --data-binary "$5" "$url/service/rest$3")
+    case "$status" in
+        2*) ;;
+        *)
+            echo "Nexus REST API: $2 $3 failed with HTTP $status: $(cat "$response")" >&2
+            exit 1
+            ;;
+    esac
+}
+{{if .AdminPassword}}
+password={{.AdminPassword}}
+{{- else}}
+password=$(LC_ALL=C tr -dc 'A-Za-z0-9' < /dev/urandom | head -c 32)
+{{- end}}
+
+call "$initial_password" PUT /v1/security/users/admin/change-password text/plain "$password"
+(umask 077 && printf '%s\n' "$password" > "$password_file")
+{{range .Calls}}
+call "$password" {{.Method}} {{.Path}} application/json {{.Body}}
+{{- end}}
+`))
+	if err := t.Execute(&buf, scriptConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
+
+// Returns the steps that run the uploaded bootstrap script, install the admin password file and then remove the script,
+// which holds the passwords. Bootstrapping is skipped if the admin password file exists, because Nexus has been
+// bootstrapped already then. The file is looked for as root, since it may be in a directory that the SSH user cannot
+// read, such as /root
+func getStepsBootstrappingNexus(homeDir string, adminPasswordFile string) []shell.Step {
+	script := filepath.Join(homeDir, bootstrapScriptFilename)
+	stagedPasswordFile := filepath.Join(homeDir, stagedAdminPasswordFilename)
+
+	return []shell.Step{
+		{
+			Name: "Bootstrapping Sonatype Nexus",
+			Commands: []string{
+				fmt.Sprintf("sh %s %s", script, stagedPasswordFile),
+				fmt.Sprintf("sudo install -D -m 0600 -o root -g root %s %s", stagedPasswordFile, adminPasswordFile),
+			},
+			Unless: fmt.Sprintf("sudo test -e %s", shell.Quote(adminPasswordFile)),
+		},
+		{
+			Name:     "Removing Sonatype Nexus bootstrap script",
+			Commands: []string{fmt.Sprintf("rm -f %s %s", script, stagedPasswordFile)},
+		},
+	}
+}
+
+func checkRequired(field string, block interface{}) []error {
+	var errs []error
+	for _, err := range validation.CheckRequired(block) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, err))
+	}
+
+	return errs
+}
+
+func checkName(field string, name string) []error {
+	if name != "" && !nexusNamePattern.MatchString(name) {
+		return []error{fmt.Errorf("%s: '%s' may contain only letters, digits, '.', '_' and '-'", field, name)}
+	}
+
+	return nil
+}
+
+// Returns an empty slice instead of nil, so that it is marshalled into an empty JSON array instead of null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
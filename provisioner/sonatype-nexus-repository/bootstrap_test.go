@@ -0,0 +1,281 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A stand-in of the Nexus REST API, which accepts the admin password in effect and records every other request
+type nexusStandIn struct {
+	mu       sync.Mutex
+	password string
+	requests []string
+	bodies   map[string]map[string]interface{}
+	failPath string
+}
+
+func newNexusStandIn(initialPassword string) *nexusStandIn {
+	return &nexusStandIn{password: initialPassword, bodies: make(map[string]map[string]interface{})}
+}
+
+func (n *nexusStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	user, password, ok := r.BasicAuth()
+	if !ok || user != "admin" || password != n.password {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	request := r.Method + " " + r.URL.Path
+	n.requests = append(n.requests, request)
+
+	if r.URL.Path == n.failPath {
+		http.Error(w, "repository already exists", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == "/service/rest/v1/security/users/admin/change-password" {
+		n.password = string(body)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n.bodies[request] = decoded
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func runBootstrapScript(t *testing.T, config Config, standIn *nexusStandIn) (string, []byte, error) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no POSIX shell to run the script with")
+	}
+	if _, err = exec.LookPath("curl"); err != nil {
+		t.Skip("no curl to run the script with")
+	}
+
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, bootstrapScriptFilename)
+	if err = os.WriteFile(script, []byte(getBootstrapScript(config)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	passwordFile := filepath.Join(dir, stagedAdminPasswordFilename)
+
+	cmd := exec.Command(sh, script, passwordFile)
+	cmd.Env = append(os.Environ(), "NEXUS_URL="+server.URL, "NEXUS_INITIAL_PASSWORD=initial-password")
+	output, err := cmd.CombinedOutput()
+
+	return passwordFile, output, err
+}
+
+func TestBootstrapScript(t *testing.T) {
+	config := Config{
+		AnonymousAccess: true,
+		BlobStores:      []BlobStore{{Name: "docker"}},
+		Repositories: []Repository{
+			{Name: "maven-all", Format: "maven", Type: "group", Members: []string{"maven-releases", "maven-central"}},
+			{Name: "maven-releases", Format: "maven", Type: "hosted"},
+			{Name: "maven-central", Format: "maven", Type: "proxy", RemoteUrl: "https://repo1.maven.org/maven2/"},
+			{Name: "docker-hub", Format: "docker", Type: "proxy", RemoteUrl: "https://registry-1.docker.io", BlobStore: "docker"},
+		},
+		Roles: []Role{{Id: "developer", Privileges: []string{"nx-repository-view-*-*-*"}}},
+		Users: []User{{UserId: "jack", FirstName: "Jack", LastName: "Q", Email: "jack@mycompany.com", Password: "it's secret", Roles: []string{"developer"}}},
+	}
+	standIn := newNexusStandIn("initial-password")
+
+	passwordFile, output, err := runBootstrapScript(t, config, standIn)
+	if err != nil {
+		t.Fatalf("Expected script to succeed, got: %v\n%s", err, output)
+	}
+
+	expectedRequests := []string{
+		"PUT /service/rest/v1/security/users/admin/change-password",
+		"POST /service/rest/v1/blobstores/file",
+		"POST /service/rest/v1/repositories/maven/hosted",
+		"POST /service/rest/v1/repositories/maven/proxy",
+		"POST /service/rest/v1/repositories/docker/proxy",
+		"POST /service/rest/v1/repositories/maven/group",
+		"POST /service/rest/v1/security/roles",
+		"POST /service/rest/v1/security/users",
+		"PUT /service/rest/v1/security/anonymous",
+	}
+	if !reflect.DeepEqual(expectedRequests, standIn.requests) {
+		t.Errorf("Expected requests %s, got: %s", expectedRequests, standIn.requests)
+	}
+
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(password) != standIn.password+"\n" || len(standIn.password) != 32 {
+		t.Errorf("Expected rotated admin password '%s' to be stored, got: '%s'", standIn.password, password)
+	}
+
+	if user := standIn.bodies["POST /service/rest/v1/security/users"]; user["password"] != "it's secret" {
+		t.Errorf("Expected user password to be sent, got: %v", user)
+	}
+	dockerProxy := standIn.bodies["POST /service/rest/v1/repositories/docker/proxy"]
+	if !reflect.DeepEqual(dockerProxy["dockerProxy"], map[string]interface{}{"indexType": "HUB"}) {
+		t.Errorf("Expected Docker Hub index, got: %v", dockerProxy)
+	}
+	if anonymous := standIn.bodies["PUT /service/rest/v1/security/anonymous"]; anonymous["enabled"] != true {
+		t.Errorf("Expected anonymous access to be enabled, got: %v", anonymous)
+	}
+}
+
+func TestBootstrapScriptWithAdminPassword(t *testing.T) {
+	standIn := newNexusStandIn("initial-password")
+
+	passwordFile, output, err := runBootstrapScript(t, Config{AdminPassword: "my 'admin' password"}, standIn)
+	if err != nil {
+		t.Fatalf("Expected script to succeed, got: %v\n%s", err, output)
+	}
+
+	if standIn.password != "my 'admin' password" {
+		t.Errorf("Expected admin password to be rotated to the configured one, got: %s", standIn.password)
+	}
+	if info, err := os.Stat(passwordFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected admin password file readable by owner only, got: %v, %v", info, err)
+	}
+}
+
+func TestBootstrapScriptFailure(t *testing.T) {
+	standIn := newNexusStandIn("initial-password")
+	standIn.failPath = "/service/rest/v1/repositories/npm/hosted"
+
+	_, output, err := runBootstrapScript(t, Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "hosted"}}}, standIn)
+	if err == nil {
+		t.Fatal("Expected script to fail")
+	}
+	if !strings.Contains(string(output), "POST /v1/repositories/npm/hosted failed with HTTP 400: repository already exists") {
+		t.Errorf("Expected failing request to be reported, got:\n%s", output)
+	}
+}
+
+func TestValidateBootstrap(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"nothing", Config{}, false},
+		{
+			"repositories in blob store",
+			Config{
+				BlobStores:   []BlobStore{{Name: "npm"}},
+				Repositories: []Repository{{Name: "npm-hosted", Format: "npm", Type: "hosted", BlobStore: "npm", WritePolicy: "allow"}},
+			},
+			false,
+		},
+		{"relative password file", Config{AdminPasswordFile: "admin.password"}, true},
+		{"short admin password", Config{AdminPassword: "admin"}, true},
+		{"missing repository type", Config{Repositories: []Repository{{Name: "npm", Format: "npm"}}}, true},
+		{"unknown format", Config{Repositories: []Repository{{Name: "pypi", Format: "pypi", Type: "hosted"}}}, true},
+		{"unknown blob store", Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "hosted", BlobStore: "npm"}}}, true},
+		{"proxy without remote", Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "proxy"}}}, true},
+		{"group without members", Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "group"}}}, true},
+		{"version policy of npm", Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "hosted", VersionPolicy: "RELEASE"}}}, true},
+		{"duplicate repository", Config{Repositories: []Repository{{Name: "npm", Format: "npm", Type: "hosted"}, {Name: "npm", Format: "npm", Type: "hosted"}}}, true},
+		{"user without roles", Config{Users: []User{{UserId: "jack", FirstName: "Jack", LastName: "Q", Email: "jack@mycompany.com", Password: "secret"}}}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateBootstrap()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestProvisionBootstrap(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"sonatypeNexusRepositoryDomain": "nexus.mycompany.com",
+		"sslCertMode":                   "self-signed",
+		"adminPassword":                 "s3cr3t-admin-password",
+		"adminPasswordFile":             "/root/nexus.password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	ui := &packersdk.BasicUi{Reader: strings.NewReader(""), Writer: &output, ErrorWriter: &output, PB: &packersdk.NoopProgressTracker{}}
+	communicator := communicatortest.New().On("sh /home/ubuntu/nexus-bootstrap", 0, "changing password to s3cr3t-admin-password\n")
+	if err = provisioner.Provision(context.Background(), ui, communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	script := communicator.Uploaded("/home/ubuntu/nexus-bootstrap")
+	if script == nil || !strings.Contains(string(script.Content), "password='s3cr3t-admin-password'\n") {
+		t.Errorf("Expected bootstrap script to be uploaded, got: %v", script)
+	}
+
+	scripts := strings.Join(communicator.Scripts(), "\n")
+	expectedCommands := []string{
+		"sh /home/ubuntu/nexus-bootstrap /home/ubuntu/nexus-admin.password\n",
+		"sudo install -D -m 0600 -o root -g root /home/ubuntu/nexus-admin.password /root/nexus.password\n",
+		"rm -f /home/ubuntu/nexus-bootstrap /home/ubuntu/nexus-admin.password\n",
+	}
+	for _, command := range expectedCommands {
+		if !strings.Contains(scripts, command) {
+			t.Errorf("Expected '%s' to run, got:\n%s", command, scripts)
+		}
+	}
+
+	if strings.Contains(output.String(), "s3cr3t-admin-password") || !strings.Contains(output.String(), "changing password to <sensitive>\n") {
+		t.Errorf("Expected admin password to be masked in Packer output, got:\n%s", output.String())
+	}
+}
+
+func TestProvisionBootstrapOnlyOnce(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"sonatypeNexusRepositoryDomain": "nexus.mycompany.com",
+		"sslCertMode":                   "self-signed",
+		"adminPassword":                 "s3cr3t-admin-password",
+		"adminPasswordFile":             "/root/nexus.password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New().On("sudo test -e '/root/nexus.password'", 0, "")
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, script := range communicator.Scripts() {
+		if strings.Contains(script, "sh /home/ubuntu/nexus-bootstrap") {
+			t.Errorf("Expected bootstrapping to be skipped once the admin password file exists, got:\n%s", script)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// DefaultDockerClientMaxBodySize The largest request body that Nginx accepts on Docker connectors, unless another one
+// is configured. "0" lifts the limit, since image layers can be of any size
+const DefaultDockerClientMaxBodySize string = "0"
+
+// DefaultDockerProxyTimeoutSeconds How long Nginx waits for Nexus to read or send a request on Docker connectors, unless
+// configured otherwise. Large layers take minutes to push
+const DefaultDockerProxyTimeoutSeconds int = 900
+
+var nginxSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// DockerConnector A port on which Nginx terminates SSL for the domain and proxies to the HTTP connector of a Docker
+// repository, so that "docker login nexus.mycompany.com:<listenPort>" reaches the repository
+type DockerConnector struct {
+	// Repository The name of the Docker repository among the repositories
+	Repository string `mapstructure:"repository" required:"true"`
+	// Port The port of the HTTP connector that Nexus opens for the repository on the loopback interface, such as 8082
+	Port int `mapstructure:"port" required:"true"`
+	// ListenPort The port on which Nginx serves the repository over SSL, such as 5000
+	ListenPort int `mapstructure:"listenPort" required:"true"`
+}
+
+func (c *Config) dockerClientMaxBodySize() string {
+	if c.DockerClientMaxBodySize == "" {
+		return DefaultDockerClientMaxBodySize
+	}
+
+	return c.DockerClientMaxBodySize
+}
+
+func (c *Config) dockerProxyTimeoutSeconds() int {
+	if c.DockerProxyTimeoutSeconds == 0 {
+		return DefaultDockerProxyTimeoutSeconds
+	}
+
+	return c.DockerProxyTimeoutSeconds
+}
+
+// Returns the port of the HTTP connector of a repository, or 0 if the repository has none
+func (c *Config) dockerConnectorPort(repository string) int {
+	for _, connector := range c.DockerConnectors {
+		if connector.Repository == repository {
+			return connector.Port
+		}
+	}
+
+	return 0
+}
+
+func (c *Config) validateDockerConnectors() []error {
+	var errs []error
+
+	dockerRepositories := make(map[string]bool)
+	for _, r := range c.Repositories {
+		if r.Format == RepositoryFormatDocker {
+			dockerRepositories[r.Name] = true
+		}
+	}
+
+	reserved := map[int]string{80: "Nginx", 443: "Nginx"}
+	if port, err := strconv.Atoi(PORT); err == nil {
+		reserved[port] = "Nexus"
+	}
+	connected := make(map[string]bool)
+	for i, connector := range c.DockerConnectors {
+		field := fmt.Sprintf("dockerConnectors[%d]", i)
+		errs = append(errs, checkRequired(field, &connector)...)
+
+		if connector.Repository != "" && !dockerRepositories[connector.Repository] {
+			errs = append(errs, fmt.Errorf("%s.repository: '%s' is not one of the Docker repositories", field, connector.Repository))
+		}
+		if connector.Repository != "" && connected[connector.Repository] {
+			errs = append(errs, fmt.Errorf("%s.repository: '%s' has more than one connector", field, connector.Repository))
+		}
+		connected[connector.Repository] = true
+
+		ports := []struct {
+			name  string
+			port  int
+			label string
+		}{
+			{"port", connector.Port, fmt.Sprintf("the connector of '%s'", connector.Repository)},
+			{"listenPort", connector.ListenPort, fmt.Sprintf("Nginx for '%s'", connector.Repository)},
+		}
+		for _, p := range ports {
+			if p.port == 0 {
+				continue
+			}
+			if p.port < 1 || p.port > 65535 {
+				errs = append(errs, fmt.Errorf("%s.%s: %d is not a valid port", field, p.name, p.port))
+			} else if user, taken := reserved[p.port]; taken {
+				errs = append(errs, fmt.Errorf("%s.%s: %d is taken by %s already", field, p.name, p.port, user))
+			}
+			reserved[p.port] = p.label
+		}
+	}
+
+	if !nginxSizePattern.MatchString(c.dockerClientMaxBodySize()) {
+		errs = append(errs, fmt.Errorf("dockerClientMaxBodySize: '%s' is not an Nginx size such as '0', '512m' or '10g'", c.DockerClientMaxBodySize))
+	}
+	if c.DockerProxyTimeoutSeconds < 0 {
+		errs = append(errs, fmt.Errorf("dockerProxyTimeoutSeconds: %d is negative", c.DockerProxyTimeoutSeconds))
+	}
+
+	return errs
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testDockerConnectorConfig() Config {
+	return Config{
+		SonatypeNexusRepositoryDomain: "nexus.mycompany.com",
+		Repositories: []Repository{
+			{Name: "docker-hosted", Format: "docker", Type: "hosted"},
+			{Name: "docker-hub", Format: "docker", Type: "proxy", RemoteUrl: "https://registry-1.docker.io"},
+		},
+		DockerConnectors: []DockerConnector{{Repository: "docker-hosted", Port: 8082, ListenPort: 5000}},
+	}
+}
+
+func TestValidateDockerConnectors(t *testing.T) {
+	data := []struct {
+		name       string
+		connectors []DockerConnector
+		maxBody    string
+		expectErr  bool
+	}{
+		{"no connectors", nil, "", false},
+		{"connectors", []DockerConnector{{"docker-hosted", 8082, 5000}, {"docker-hub", 8083, 5001}}, "10g", false},
+		{"unknown repository", []DockerConnector{{"maven-releases", 8082, 5000}}, "", true},
+		{"missing listen port", []DockerConnector{{Repository: "docker-hosted", Port: 8082}}, "", true},
+		{"port of Nexus", []DockerConnector{{"docker-hosted", 8081, 5000}}, "", true},
+		{"listen port of Nginx", []DockerConnector{{"docker-hosted", 8082, 443}}, "", true},
+		{"same port and listen port", []DockerConnector{{"docker-hosted", 8082, 8082}}, "", true},
+		{"shared listen port", []DockerConnector{{"docker-hosted", 8082, 5000}, {"docker-hub", 8083, 5000}}, "", true},
+		{"two connectors of a repository", []DockerConnector{{"docker-hosted", 8082, 5000}, {"docker-hosted", 8083, 5001}}, "", true},
+		{"invalid port", []DockerConnector{{"docker-hosted", 70000, 5000}}, "", true},
+		{"invalid body size", nil, "10 GB", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			config := testDockerConnectorConfig()
+			config.DockerConnectors = d.connectors
+			config.DockerClientMaxBodySize = d.maxBody
+
+			errs := config.validateDockerConnectors()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestGetNginxConfigWithDockerConnectors(t *testing.T) {
+	config := getNginxConfig(testDockerConnectorConfig())
+
+	expectedSnippets := []string{
+		"    client_max_body_size 0;\n",
+		"    proxy_request_buffering off;\n",
+		"    proxy_read_timeout 900s;\n",
+		"        proxy_pass http://127.0.0.1:8082;\n",
+		"    listen 5000 ssl;\n",
+	}
+	for _, snippet := range expectedSnippets {
+		if !strings.Contains(config, snippet) {
+			t.Errorf("Expected '%s' in Nginx config:\n%s", snippet, config)
+		}
+	}
+
+	if withoutConnectors := getNginxConfig(Config{SonatypeNexusRepositoryDomain: "nexus.mycompany.com"}); strings.Contains(withoutConnectors, "client_max_body_size") {
+		t.Errorf("Expected no connector servers without connectors, got:\n%s", withoutConnectors)
+	}
+}
+
+func TestDockerConnectorPorts(t *testing.T) {
+	config := testDockerConnectorConfig()
+
+	if composeFile := getComposeFile(config); !strings.Contains(composeFile, `      - "127.0.0.1:8082:8082"`+"\n") {
+		t.Errorf("Expected connector port to be published on the loopback interface, got:\n%s", composeFile)
+	}
+
+	httpPorts := make(map[string]interface{})
+	for _, call := range getApiCalls(config) {
+		if !strings.HasPrefix(call.Path, "/v1/repositories/docker/") {
+			continue
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.Trim(call.Body, "'")), &body); err != nil {
+			t.Fatal(err)
+		}
+		httpPorts[body["name"].(string)] = body["docker"].(map[string]interface{})["httpPort"]
+	}
+	if httpPorts["docker-hosted"] != float64(8082) || httpPorts["docker-hub"] != nil {
+		t.Errorf("Expected only docker-hosted to open a connector on 8082, got: %v", httpPorts)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultNexusImage The Sonatype Nexus image that is run, unless another one is configured. It is pinned, so that
+// images built at different times run the same Nexus
+const DefaultNexusImage string = "sonatype/nexus3:3.70.1"
+
+// DefaultNexusHeapSize The JVM heap size of Nexus, unless another one is configured. It is the default of Nexus itself
+const DefaultNexusHeapSize string = "2703m"
+
+// DefaultNexusStartupTimeoutMinutes How long the build waits for Nexus to become healthy, unless configured otherwise
+const DefaultNexusStartupTimeoutMinutes int = 10
+
+// ContainerName The name of the Nexus container
+const ContainerName string = "nexus"
+
+// ComposeDir The directory that holds the compose file running Nexus
+const ComposeDir string = "/opt/nexus"
+
+const dataVolume string = "nexus-data"
+const composeFilename string = "compose.yaml"
+const imageArchiveFilename string = "nexus-image.tar"
+
+var jvmSizePattern = regexp.MustCompile(`^[1-9][0-9]*[kKmMgG]?$`)
+
+func (c *Config) nexusImage() string {
+	if c.NexusImage == "" {
+		return DefaultNexusImage
+	}
+
+	return c.NexusImage
+}
+
+func (c *Config) nexusHeapSize() string {
+	if c.NexusHeapSize == "" {
+		return DefaultNexusHeapSize
+	}
+
+	return c.NexusHeapSize
+}
+
+// Returns the maximum direct memory of Nexus, which defaults to the heap size as Nexus recommends
+func (c *Config) nexusMaxDirectMemorySize() string {
+	if c.NexusMaxDirectMemorySize == "" {
+		return c.nexusHeapSize()
+	}
+
+	return c.NexusMaxDirectMemorySize
+}
+
+func (c *Config) nexusStartupTimeout() time.Duration {
+	if c.NexusStartupTimeoutMinutes == 0 {
+		return time.Duration(DefaultNexusStartupTimeoutMinutes) * time.Minute
+	}
+
+	return time.Duration(c.NexusStartupTimeoutMinutes) * time.Minute
+}
+
+func (c *Config) validateNexus() []error {
+	var errs []error
+
+	if !isPinnedImage(c.nexusImage()) {
+		errs = append(errs, fmt.Errorf("nexusImage: '%s' is not pinned to a version tag or digest", c.NexusImage))
+	}
+	if !jvmSizePattern.MatchString(c.nexusHeapSize()) {
+		errs = append(errs, fmt.Errorf("nexusHeapSize: '%s' is not a JVM memory size such as '2703m' or '4g'", c.NexusHeapSize))
+	}
+	if !jvmSizePattern.MatchString(c.nexusMaxDirectMemorySize()) {
+		errs = append(errs, fmt.Errorf("nexusMaxDirectMemorySize: '%s' is not a JVM memory size such as '2703m' or '4g'", c.NexusMaxDirectMemorySize))
+	}
+	if c.NexusStartupTimeoutMinutes < 0 {
+		errs = append(errs, fmt.Errorf("nexusStartupTimeoutMinutes: %d is negative", c.NexusStartupTimeoutMinutes))
+	}
+
+	return errs
+}
+
+// Returns whether an image reference names a digest or a tag other than "latest"
+func isPinnedImage(image string) bool {
+	if strings.Contains(image, "@sha256:") {
+		return true
+	}
+
+	// A colon before the last slash separates a registry port rather than a tag
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+
+	return found && tag != "" && tag != "latest"
+}
+
+// Returns the compose file that runs Nexus with its data in the nexus-data volume. Nexus and the HTTP connectors of its
+// Docker repositories listen on the loopback interface only, where Nginx reaches them, and Nexus is restarted by Docker
+// whenever it fails as well as on boot
+func getComposeFile(config Config) string {
+	var composeConfigs = struct {
+		Image         string
+		ContainerName string
+		Port          string
+		Connectors    []DockerConnector
+		VmParams      string
+		DataVolume    string
+	}{
+		config.nexusImage(),
+		ContainerName,
+		PORT,
+		config.DockerConnectors,
+		getVmParams(config),
+		dataVolume,
+	}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("Nexus Compose File").Parse(`services:
+  nexus:
+    image: {{.Image}}
+    container_name: {{.ContainerName}}
+    restart: unless-stopped
+    ports:
+      - "127.0.0.1:{{.Port}}:8081"
+{{- range .Connectors}}
+      - "127.0.0.1:{{.Port}}:{{.Port}}"
+{{- end}}
+    environment:
+      INSTALL4J_ADD_VM_PARAMS: "{{.VmParams}}"
+    volumes:
+      - {{.DataVolume}}:/nexus-data
+    stop_grace_period: 2m
+    healthcheck:
+      test: ["CMD-SHELL", "curl -fsS http://localhost:8081/service/rest/v1/status || exit 1"]
+      interval: 15s
+      timeout: 10s
+      retries: 3
+      start_period: 5m
+
+volumes:
+  {{.DataVolume}}:
+    external: true
+`))
+	if err := t.Execute(&buf, composeConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
+
+// Returns the JVM parameters of Nexus, which replace the memory settings of the image
+func getVmParams(config Config) string {
+	return strings.Join([]string{
+		fmt.Sprintf("-Xms%s", config.nexusHeapSize()),
+		fmt.Sprintf("-Xmx%s", config.nexusHeapSize()),
+		fmt.Sprintf("-XX:MaxDirectMemorySize=%s", config.nexusMaxDirectMemorySize()),
+		"-Djava.util.prefs.userRoot=/nexus-data/javaprefs",
+	}, " ")
+}
+
+// Returns the step that pulls the Nexus image or, if its archive has been uploaded, loads it from the archive
+func getStepProvidingImage(homeDir string, image string, imageUploaded bool) shell.Step {
+	if !imageUploaded {
+		return shell.Step{Name: fmt.Sprintf("Pulling %s", image), Commands: []string{fmt.Sprintf("docker pull %s", image)}}
+	}
+
+	archive := filepath.Join(homeDir, imageArchiveFilename)
+	return shell.Step{
+		Name: fmt.Sprintf("Loading %s", image),
+		Commands: []string{
+			fmt.Sprintf("docker load -i %s", archive),
+			fmt.Sprintf("rm -f %s", archive),
+		},
+	}
+}
+
+// Returns the step that moves the uploaded compose file into place and starts Nexus
+func getStepStartingNexus(homeDir string) shell.Step {
+	composeFile := filepath.Join(ComposeDir, composeFilename)
+
+	return shell.Step{
+		Name: "Starting Sonatype Nexus",
+		Commands: []string{
+			fmt.Sprintf("sudo mkdir -p %s", ComposeDir),
+			fmt.Sprintf("sudo mv %s %s", filepath.Join(homeDir, "nexus-"+composeFilename), composeFile),
+			fmt.Sprintf("docker compose -f %s up -d", composeFile),
+		},
+	}
+}
+
+// Returns the step that waits until the health check of the Nexus container passes. It fails as soon as the container
+// stops running, printing the tail of its logs, and otherwise once the timeout is exceeded
+func getStepWaitingForNexus(timeout time.Duration) shell.Step {
+	return shell.Step{
+		Name: "Waiting for Sonatype Nexus to become healthy",
+		Commands: []string{fmt.Sprintf(
+			`until [ "$(docker inspect --format '{{.State.Health.Status}}' %[1]s)" = healthy ]; do `+
+				`if [ "$(docker inspect --format '{{.State.Status}}' %[1]s)" != running ]; then docker logs --tail 100 %[1]s; exit 1; fi; `+
+				`sleep 5; `+
+				`done`,
+			ContainerName,
+		)},
+		Timeout: timeout,
+	}
+}
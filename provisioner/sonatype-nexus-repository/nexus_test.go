@@ -0,0 +1,149 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPinnedImage(t *testing.T) {
+	data := []struct {
+		image    string
+		expected bool
+	}{
+		{"sonatype/nexus3:3.70.1", true},
+		{"registry.mycompany.com:5000/sonatype/nexus3:3.70.1", true},
+		{"sonatype/nexus3@sha256:0123456789abcdef", true},
+		{"sonatype/nexus3", false},
+		{"sonatype/nexus3:latest", false},
+		{"registry.mycompany.com:5000/sonatype/nexus3", false},
+	}
+
+	for _, d := range data {
+		t.Run(d.image, func(t *testing.T) {
+			if actual := isPinnedImage(d.image); actual != d.expected {
+				t.Errorf("Expected pinned: %t, got: %t", d.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateNexus(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"defaults", Config{}, false},
+		{"custom image and memory", Config{NexusImage: "sonatype/nexus3:3.68.0", NexusHeapSize: "4g", NexusMaxDirectMemorySize: "2048m"}, false},
+		{"unpinned image", Config{NexusImage: "sonatype/nexus3:latest"}, true},
+		{"invalid heap size", Config{NexusHeapSize: "4 GB"}, true},
+		{"negative timeout", Config{NexusStartupTimeoutMinutes: -1}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateNexus()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestGetComposeFile(t *testing.T) {
+	composeFile := getComposeFile(Config{NexusHeapSize: "4g"})
+
+	expectedLines := []string{
+		"    image: sonatype/nexus3:3.70.1",
+		"    restart: unless-stopped",
+		`      - "127.0.0.1:8081:8081"`,
+		`      INSTALL4J_ADD_VM_PARAMS: "-Xms4g -Xmx4g -XX:MaxDirectMemorySize=4g -Djava.util.prefs.userRoot=/nexus-data/javaprefs"`,
+		"      - nexus-data:/nexus-data",
+		"    external: true",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(composeFile, line+"\n") {
+			t.Errorf("Expected '%s' in compose file:\n%s", line, composeFile)
+		}
+	}
+}
+
+func TestProvisionStartsNexus(t *testing.T) {
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"sonatypeNexusRepositoryDomain": "nexus.mycompany.com",
+		"sslCertMode":                   "self-signed",
+		"nexusStartupTimeoutMinutes":    15,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	composeFile := communicator.Uploaded("/home/ubuntu/nexus-compose.yaml")
+	if composeFile == nil || string(composeFile.Content) != getComposeFile(provisioner.config) {
+		t.Errorf("Expected compose file to be uploaded, got: %v", composeFile)
+	}
+
+	scripts := strings.Join(communicator.Scripts(), "\n")
+	expectedCommands := []string{
+		"docker pull sonatype/nexus3:3.70.1\n",
+		"docker compose -f /opt/nexus/compose.yaml up -d\n",
+		"docker inspect --format '{{.State.Health.Status}}' nexus",
+	}
+	for _, command := range expectedCommands {
+		if !strings.Contains(scripts, command) {
+			t.Errorf("Expected '%s' to run, got:\n%s", command, scripts)
+		}
+	}
+
+	if timeout := provisioner.config.nexusStartupTimeout(); timeout != 15*time.Minute {
+		t.Errorf("Expected 15-minute startup timeout, got: %s", timeout)
+	}
+}
+
+func TestProvisionOfflineImage(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "nexus3.tar")
+	if err := os.WriteFile(image, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"sonatypeNexusRepositoryDomain": "nexus.mycompany.com",
+		"sslCertMode":                   "self-signed",
+		"offline":                       true,
+		"dockerPackagesSource":          t.TempDir(),
+		"nexusImageSource":              image,
+		"nginxPackagesSource":           t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if communicator.Uploaded("/home/ubuntu/nexus-image.tar") == nil {
+		t.Error("Expected image archive to be uploaded")
+	}
+	scripts := strings.Join(communicator.Scripts(), "\n")
+	if !strings.Contains(scripts, "docker load -i /home/ubuntu/nexus-image.tar\n") || strings.Contains(scripts, "docker pull") {
+		t.Errorf("Expected image to be loaded instead of pulled, got:\n%s", scripts)
+	}
+}
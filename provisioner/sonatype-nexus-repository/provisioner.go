@@ -1,19 +1,25 @@
 // Copyright (c) Jiaqi Liu
 // SPDX-License-Identifier: MPL-2.0
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,BlobStore,Repository,Role,User,DockerConnector
 
 package artifactory
 
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/render"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"path/filepath"
 	"text/template"
 )
 
@@ -21,11 +27,33 @@ import (
 const PORT string = "8081"
 
 type Config struct {
-	SslCertBase64                 string `mapstructure:"sslCertBase64" required:"true"`
-	SslCertKeyBase64              string `mapstructure:"sslCertKeyBase64" required:"true"`
 	SonatypeNexusRepositoryDomain string `mapstructure:"sonatypeNexusRepositoryDomain" required:"true"`
 	HomeDir                       string `mapstructure:"homeDir" required:"false"`
 
+	NexusImage                 string `mapstructure:"nexusImage" required:"false"`
+	NexusHeapSize              string `mapstructure:"nexusHeapSize" required:"false"`
+	NexusMaxDirectMemorySize   string `mapstructure:"nexusMaxDirectMemorySize" required:"false"`
+	NexusStartupTimeoutMinutes int    `mapstructure:"nexusStartupTimeoutMinutes" required:"false"`
+
+	AdminPassword     string       `mapstructure:"adminPassword" required:"false"`
+	AdminPasswordFile string       `mapstructure:"adminPasswordFile" required:"false"`
+	AnonymousAccess   bool         `mapstructure:"anonymousAccess" required:"false"`
+	BlobStores        []BlobStore  `mapstructure:"blobStores" required:"false"`
+	Repositories      []Repository `mapstructure:"repositories" required:"false"`
+	Roles             []Role       `mapstructure:"roles" required:"false"`
+	Users             []User       `mapstructure:"users" required:"false"`
+
+	DockerConnectors          []DockerConnector `mapstructure:"dockerConnectors" required:"false"`
+	DockerClientMaxBodySize   string            `mapstructure:"dockerClientMaxBodySize" required:"false"`
+	DockerProxyTimeoutSeconds int               `mapstructure:"dockerProxyTimeoutSeconds" required:"false"`
+
+	Offline              bool   `mapstructure:"offline" required:"false"`
+	DockerPackagesSource string `mapstructure:"dockerPackagesSource" required:"false"`
+	NexusImageSource     string `mapstructure:"nexusImageSource" required:"false"`
+
+	Ssl    ssl.Config    `mapstructure:",squash"`
+	DryRun render.Config `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -43,12 +71,59 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		return err
 	}
 
-	return nil
+	errs := validation.CheckRequired(&p.config)
+	errs = append(errs, validation.CheckDomain("sonatypeNexusRepositoryDomain", p.config.SonatypeNexusRepositoryDomain))
+	errs = append(errs, p.config.Ssl.Validate(p.config.SonatypeNexusRepositoryDomain)...)
+	errs = append(
+		errs,
+		validation.CheckOfflineSource(p.config.Offline, "dockerPackagesSource", p.config.DockerPackagesSource),
+		validation.CheckOfflineSource(p.config.Offline, "nexusImageSource", p.config.NexusImageSource),
+		p.config.Ssl.CheckOffline(p.config.Offline),
+	)
+	errs = append(errs, p.config.validateNexus()...)
+	errs = append(errs, p.config.validateBootstrap()...)
+	errs = append(errs, p.config.validateDockerConnectors()...)
+	errs = append(errs, p.config.DryRun.Validate()...)
+
+	return validation.Combine(errs...)
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, generatedData map[string]interface{}) error {
+	return p.config.DryRun.Run(ctx, ui, communicator, p.provision)
+}
+
+func (p *Provisioner) provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
 	p.config.HomeDir = ssl.GetHomeDir(p.config.HomeDir)
-	err := shell.Provision(ctx, ui, communicator, getCommands())
+
+	d, err := distro.Detect(ctx, communicator)
+	if err != nil {
+		return err
+	}
+
+	dockerStep, err := shell.StepInstallingDocker(p.config.ctx, ui, communicator, d, p.config.HomeDir, p.config.DockerPackagesSource)
+	if err != nil {
+		return err
+	}
+
+	if p.config.NexusImageSource != "" {
+		err = file.Provision(p.config.ctx, ui, communicator, p.config.NexusImageSource, filepath.Join(p.config.HomeDir, imageArchiveFilename))
+		if err != nil {
+			return err
+		}
+	}
+
+	composeFileDst := filepath.Join(p.config.HomeDir, "nexus-"+composeFilename)
+	if err = ssl.UploadContent(p.config.ctx, ui, communicator, getComposeFile(p.config), composeFileDst); err != nil {
+		return err
+	}
+
+	sensitive := p.config.bootstrapSensitiveValues()
+	bootstrapScriptDst := filepath.Join(p.config.HomeDir, bootstrapScriptFilename)
+	if err = ssl.UploadContent(p.config.ctx, ui, communicator, getBootstrapScript(p.config), bootstrapScriptDst); err != nil {
+		return err
+	}
+
+	err = shell.Provision(ctx, ui, communicator, getSteps(dockerStep, p.config), sensitive...)
 	if err != nil {
 		return err
 	}
@@ -57,24 +132,52 @@ func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicat
 		p.config.ctx,
 		ui,
 		communicator,
+		d,
 		p.config.HomeDir,
-		p.config.SslCertBase64,
-		p.config.SslCertKeyBase64,
-		getNginxConfig(p.config.SonatypeNexusRepositoryDomain),
+		p.config.Ssl,
+		p.config.SonatypeNexusRepositoryDomain,
+		getNginxConfig(p.config),
 	)
 }
 
-func getCommands() []string {
-	return append(shell.CommandsInstallingSudoLessDocker(), []string{"docker volume create --name nexus-data"}...)
+// Returns the steps that install Docker, run Nexus on it, wait for Nexus to become healthy and then bootstrap it
+func getSteps(dockerStep shell.Step, config Config) []shell.Step {
+	steps := []shell.Step{
+		dockerStep,
+		{
+			Name:     "Creating Nexus data volume",
+			Commands: []string{fmt.Sprintf("docker volume create --name %s", dataVolume)},
+			Unless:   fmt.Sprintf("docker volume inspect %s", dataVolume),
+		},
+		getStepProvidingImage(config.HomeDir, config.nexusImage(), config.NexusImageSource != ""),
+		getStepStartingNexus(config.HomeDir),
+		getStepWaitingForNexus(config.nexusStartupTimeout()),
+	}
+
+	return append(steps, getStepsBootstrappingNexus(config.HomeDir, config.adminPasswordFile())...)
 }
 
-func getNginxConfig(domain string) string {
+// Returns the Nginx config that terminates SSL for the domain and proxies to Nexus. Every Docker connector gets a server
+// of its own on its listen port, which accepts request bodies of up to the configured size and waits as long as the
+// configured timeout, so that large image layers can be pushed. Requests are streamed to Nexus rather than buffered
+func getNginxConfig(config Config) string {
 	var sslConfigs = struct {
-		Domain        string
-		SslCertDst    string
-		SslCertKeyDst string
-		Port          string
-	}{domain, ssl.SslCertDst, ssl.SslCertKeyDst, PORT}
+		Domain            string
+		SslCertDst        string
+		SslCertKeyDst     string
+		Port              string
+		Connectors        []DockerConnector
+		ClientMaxBodySize string
+		TimeoutSeconds    int
+	}{
+		config.SonatypeNexusRepositoryDomain,
+		ssl.SslCertDst,
+		ssl.SslCertKeyDst,
+		PORT,
+		config.DockerConnectors,
+		config.dockerClientMaxBodySize(),
+		config.dockerProxyTimeoutSeconds(),
+	}
 	var buf bytes.Buffer
 	t := template.Must(template.New("Nginx Config").Parse(`
 server {
@@ -119,6 +222,34 @@ server {
     server_name {{.Domain}};
     return 404;
 }
+{{- range .Connectors}}
+
+server {
+    server_name {{$.Domain}};
+
+    client_max_body_size {{$.ClientMaxBodySize}};
+    chunked_transfer_encoding on;
+    proxy_request_buffering off;
+    proxy_buffering off;
+    proxy_connect_timeout 10s;
+    proxy_send_timeout {{$.TimeoutSeconds}}s;
+    proxy_read_timeout {{$.TimeoutSeconds}}s;
+    send_timeout {{$.TimeoutSeconds}}s;
+
+    location / {
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_set_header Host $host:$server_port;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto https;
+    }
+
+    listen [::]:{{.ListenPort}} ssl;
+    listen {{.ListenPort}} ssl;
+    ssl_certificate {{$.SslCertDst}};
+    ssl_certificate_key {{$.SslCertKeyDst}};
+}
+{{- end}}
 	`))
 
 	if err := t.Execute(&buf, sslConfigs); err != nil {
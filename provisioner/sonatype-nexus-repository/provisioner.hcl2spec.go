@@ -7,13 +7,72 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
+// FlatBlobStore is an auto-generated flat version of BlobStore.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatBlobStore struct {
+	Name *string `mapstructure:"name" required:"true" cty:"name" hcl:"name"`
+	Path *string `mapstructure:"path" required:"false" cty:"path" hcl:"path"`
+}
+
+// FlatMapstructure returns a new FlatBlobStore.
+// FlatBlobStore is an auto-generated flat version of BlobStore.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*BlobStore) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatBlobStore)
+}
+
+// HCL2Spec returns the hcl spec of a BlobStore.
+// This spec is used by HCL to read the fields of BlobStore.
+// The decoded values from this spec will then be applied to a FlatBlobStore.
+func (*FlatBlobStore) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name": &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"path": &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+	}
+	return s
+}
+
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	SslCertBase64                 *string `mapstructure:"sslCertBase64" required:"true" cty:"sslCertBase64" hcl:"sslCertBase64"`
-	SslCertKeyBase64              *string `mapstructure:"sslCertKeyBase64" required:"true" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
-	SonatypeNexusRepositoryDomain *string `mapstructure:"sonatypeNexusRepositoryDomain" required:"true" cty:"sonatypeNexusRepositoryDomain" hcl:"sonatypeNexusRepositoryDomain"`
-	HomeDir                       *string `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	SonatypeNexusRepositoryDomain *string               `mapstructure:"sonatypeNexusRepositoryDomain" required:"true" cty:"sonatypeNexusRepositoryDomain" hcl:"sonatypeNexusRepositoryDomain"`
+	HomeDir                       *string               `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	NexusImage                    *string               `mapstructure:"nexusImage" required:"false" cty:"nexusImage" hcl:"nexusImage"`
+	NexusHeapSize                 *string               `mapstructure:"nexusHeapSize" required:"false" cty:"nexusHeapSize" hcl:"nexusHeapSize"`
+	NexusMaxDirectMemorySize      *string               `mapstructure:"nexusMaxDirectMemorySize" required:"false" cty:"nexusMaxDirectMemorySize" hcl:"nexusMaxDirectMemorySize"`
+	NexusStartupTimeoutMinutes    *int                  `mapstructure:"nexusStartupTimeoutMinutes" required:"false" cty:"nexusStartupTimeoutMinutes" hcl:"nexusStartupTimeoutMinutes"`
+	AdminPassword                 *string               `mapstructure:"adminPassword" required:"false" cty:"adminPassword" hcl:"adminPassword"`
+	AdminPasswordFile             *string               `mapstructure:"adminPasswordFile" required:"false" cty:"adminPasswordFile" hcl:"adminPasswordFile"`
+	AnonymousAccess               *bool                 `mapstructure:"anonymousAccess" required:"false" cty:"anonymousAccess" hcl:"anonymousAccess"`
+	BlobStores                    []FlatBlobStore       `mapstructure:"blobStores" required:"false" cty:"blobStores" hcl:"blobStores"`
+	Repositories                  []FlatRepository      `mapstructure:"repositories" required:"false" cty:"repositories" hcl:"repositories"`
+	Roles                         []FlatRole            `mapstructure:"roles" required:"false" cty:"roles" hcl:"roles"`
+	Users                         []FlatUser            `mapstructure:"users" required:"false" cty:"users" hcl:"users"`
+	DockerConnectors              []FlatDockerConnector `mapstructure:"dockerConnectors" required:"false" cty:"dockerConnectors" hcl:"dockerConnectors"`
+	DockerClientMaxBodySize       *string               `mapstructure:"dockerClientMaxBodySize" required:"false" cty:"dockerClientMaxBodySize" hcl:"dockerClientMaxBodySize"`
+	DockerProxyTimeoutSeconds     *int                  `mapstructure:"dockerProxyTimeoutSeconds" required:"false" cty:"dockerProxyTimeoutSeconds" hcl:"dockerProxyTimeoutSeconds"`
+	Offline                       *bool                 `mapstructure:"offline" required:"false" cty:"offline" hcl:"offline"`
+	DockerPackagesSource          *string               `mapstructure:"dockerPackagesSource" required:"false" cty:"dockerPackagesSource" hcl:"dockerPackagesSource"`
+	NexusImageSource              *string               `mapstructure:"nexusImageSource" required:"false" cty:"nexusImageSource" hcl:"nexusImageSource"`
+	SslCertMode                   *string               `mapstructure:"sslCertMode" required:"false" cty:"sslCertMode" hcl:"sslCertMode"`
+	SslCertBase64                 *string               `mapstructure:"sslCertBase64" required:"false" cty:"sslCertBase64" hcl:"sslCertBase64"`
+	SslCertKeyBase64              *string               `mapstructure:"sslCertKeyBase64" required:"false" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
+	SslCertFile                   *string               `mapstructure:"sslCertFile" required:"false" cty:"sslCertFile" hcl:"sslCertFile"`
+	SslCertKeyFile                *string               `mapstructure:"sslCertKeyFile" required:"false" cty:"sslCertKeyFile" hcl:"sslCertKeyFile"`
+	SslCertEnv                    *string               `mapstructure:"sslCertEnv" required:"false" cty:"sslCertEnv" hcl:"sslCertEnv"`
+	SslCertKeyEnv                 *string               `mapstructure:"sslCertKeyEnv" required:"false" cty:"sslCertKeyEnv" hcl:"sslCertKeyEnv"`
+	SslChainFile                  *string               `mapstructure:"sslChainFile" required:"false" cty:"sslChainFile" hcl:"sslChainFile"`
+	SslCertExpiryWindowDays       *int                  `mapstructure:"sslCertExpiryWindowDays" required:"false" cty:"sslCertExpiryWindowDays" hcl:"sslCertExpiryWindowDays"`
+	SslCertFailWithinExpiryWindow *bool                 `mapstructure:"sslCertFailWithinExpiryWindow" required:"false" cty:"sslCertFailWithinExpiryWindow" hcl:"sslCertFailWithinExpiryWindow"`
+	SslCaCertBase64               *string               `mapstructure:"sslCaCertBase64" required:"false" cty:"sslCaCertBase64" hcl:"sslCaCertBase64"`
+	SslCaKeyBase64                *string               `mapstructure:"sslCaKeyBase64" required:"false" cty:"sslCaKeyBase64" hcl:"sslCaKeyBase64"`
+	SslSelfSignedValidityDays     *int                  `mapstructure:"sslSelfSignedValidityDays" required:"false" cty:"sslSelfSignedValidityDays" hcl:"sslSelfSignedValidityDays"`
+	SslAcmeEmail                  *string               `mapstructure:"sslAcmeEmail" required:"false" cty:"sslAcmeEmail" hcl:"sslAcmeEmail"`
+	SslAcmeDirectoryUrl           *string               `mapstructure:"sslAcmeDirectoryUrl" required:"false" cty:"sslAcmeDirectoryUrl" hcl:"sslAcmeDirectoryUrl"`
+	SslAcmeDirectoryCaBase64      *string               `mapstructure:"sslAcmeDirectoryCaBase64" required:"false" cty:"sslAcmeDirectoryCaBase64" hcl:"sslAcmeDirectoryCaBase64"`
+	NginxPackagesSource           *string               `mapstructure:"nginxPackagesSource" required:"false" cty:"nginxPackagesSource" hcl:"nginxPackagesSource"`
+	DryRunDir                     *string               `mapstructure:"dryRunDir" required:"false" cty:"dryRunDir" hcl:"dryRunDir"`
+	DryRunDistro                  *string               `mapstructure:"dryRunDistro" required:"false" cty:"dryRunDistro" hcl:"dryRunDistro"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -28,10 +87,172 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"sslCertBase64":                 &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
-		"sslCertKeyBase64":              &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
 		"sonatypeNexusRepositoryDomain": &hcldec.AttrSpec{Name: "sonatypeNexusRepositoryDomain", Type: cty.String, Required: false},
 		"homeDir":                       &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"nexusImage":                    &hcldec.AttrSpec{Name: "nexusImage", Type: cty.String, Required: false},
+		"nexusHeapSize":                 &hcldec.AttrSpec{Name: "nexusHeapSize", Type: cty.String, Required: false},
+		"nexusMaxDirectMemorySize":      &hcldec.AttrSpec{Name: "nexusMaxDirectMemorySize", Type: cty.String, Required: false},
+		"nexusStartupTimeoutMinutes":    &hcldec.AttrSpec{Name: "nexusStartupTimeoutMinutes", Type: cty.Number, Required: false},
+		"adminPassword":                 &hcldec.AttrSpec{Name: "adminPassword", Type: cty.String, Required: false},
+		"adminPasswordFile":             &hcldec.AttrSpec{Name: "adminPasswordFile", Type: cty.String, Required: false},
+		"anonymousAccess":               &hcldec.AttrSpec{Name: "anonymousAccess", Type: cty.Bool, Required: false},
+		"blobStores":                    &hcldec.BlockListSpec{TypeName: "blobStores", Nested: hcldec.ObjectSpec((*FlatBlobStore)(nil).HCL2Spec())},
+		"repositories":                  &hcldec.BlockListSpec{TypeName: "repositories", Nested: hcldec.ObjectSpec((*FlatRepository)(nil).HCL2Spec())},
+		"roles":                         &hcldec.BlockListSpec{TypeName: "roles", Nested: hcldec.ObjectSpec((*FlatRole)(nil).HCL2Spec())},
+		"users":                         &hcldec.BlockListSpec{TypeName: "users", Nested: hcldec.ObjectSpec((*FlatUser)(nil).HCL2Spec())},
+		"dockerConnectors":              &hcldec.BlockListSpec{TypeName: "dockerConnectors", Nested: hcldec.ObjectSpec((*FlatDockerConnector)(nil).HCL2Spec())},
+		"dockerClientMaxBodySize":       &hcldec.AttrSpec{Name: "dockerClientMaxBodySize", Type: cty.String, Required: false},
+		"dockerProxyTimeoutSeconds":     &hcldec.AttrSpec{Name: "dockerProxyTimeoutSeconds", Type: cty.Number, Required: false},
+		"offline":                       &hcldec.AttrSpec{Name: "offline", Type: cty.Bool, Required: false},
+		"dockerPackagesSource":          &hcldec.AttrSpec{Name: "dockerPackagesSource", Type: cty.String, Required: false},
+		"nexusImageSource":              &hcldec.AttrSpec{Name: "nexusImageSource", Type: cty.String, Required: false},
+		"sslCertMode":                   &hcldec.AttrSpec{Name: "sslCertMode", Type: cty.String, Required: false},
+		"sslCertBase64":                 &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
+		"sslCertKeyBase64":              &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
+		"sslCertFile":                   &hcldec.AttrSpec{Name: "sslCertFile", Type: cty.String, Required: false},
+		"sslCertKeyFile":                &hcldec.AttrSpec{Name: "sslCertKeyFile", Type: cty.String, Required: false},
+		"sslCertEnv":                    &hcldec.AttrSpec{Name: "sslCertEnv", Type: cty.String, Required: false},
+		"sslCertKeyEnv":                 &hcldec.AttrSpec{Name: "sslCertKeyEnv", Type: cty.String, Required: false},
+		"sslChainFile":                  &hcldec.AttrSpec{Name: "sslChainFile", Type: cty.String, Required: false},
+		"sslCertExpiryWindowDays":       &hcldec.AttrSpec{Name: "sslCertExpiryWindowDays", Type: cty.Number, Required: false},
+		"sslCertFailWithinExpiryWindow": &hcldec.AttrSpec{Name: "sslCertFailWithinExpiryWindow", Type: cty.Bool, Required: false},
+		"sslCaCertBase64":               &hcldec.AttrSpec{Name: "sslCaCertBase64", Type: cty.String, Required: false},
+		"sslCaKeyBase64":                &hcldec.AttrSpec{Name: "sslCaKeyBase64", Type: cty.String, Required: false},
+		"sslSelfSignedValidityDays":     &hcldec.AttrSpec{Name: "sslSelfSignedValidityDays", Type: cty.Number, Required: false},
+		"sslAcmeEmail":                  &hcldec.AttrSpec{Name: "sslAcmeEmail", Type: cty.String, Required: false},
+		"sslAcmeDirectoryUrl":           &hcldec.AttrSpec{Name: "sslAcmeDirectoryUrl", Type: cty.String, Required: false},
+		"sslAcmeDirectoryCaBase64":      &hcldec.AttrSpec{Name: "sslAcmeDirectoryCaBase64", Type: cty.String, Required: false},
+		"nginxPackagesSource":           &hcldec.AttrSpec{Name: "nginxPackagesSource", Type: cty.String, Required: false},
+		"dryRunDir":                     &hcldec.AttrSpec{Name: "dryRunDir", Type: cty.String, Required: false},
+		"dryRunDistro":                  &hcldec.AttrSpec{Name: "dryRunDistro", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatDockerConnector is an auto-generated flat version of DockerConnector.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDockerConnector struct {
+	Repository *string `mapstructure:"repository" required:"true" cty:"repository" hcl:"repository"`
+	Port       *int    `mapstructure:"port" required:"true" cty:"port" hcl:"port"`
+	ListenPort *int    `mapstructure:"listenPort" required:"true" cty:"listenPort" hcl:"listenPort"`
+}
+
+// FlatMapstructure returns a new FlatDockerConnector.
+// FlatDockerConnector is an auto-generated flat version of DockerConnector.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DockerConnector) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDockerConnector)
+}
+
+// HCL2Spec returns the hcl spec of a DockerConnector.
+// This spec is used by HCL to read the fields of DockerConnector.
+// The decoded values from this spec will then be applied to a FlatDockerConnector.
+func (*FlatDockerConnector) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"repository": &hcldec.AttrSpec{Name: "repository", Type: cty.String, Required: false},
+		"port":       &hcldec.AttrSpec{Name: "port", Type: cty.Number, Required: false},
+		"listenPort": &hcldec.AttrSpec{Name: "listenPort", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlatRepository is an auto-generated flat version of Repository.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRepository struct {
+	Name          *string  `mapstructure:"name" required:"true" cty:"name" hcl:"name"`
+	Format        *string  `mapstructure:"format" required:"true" cty:"format" hcl:"format"`
+	Type          *string  `mapstructure:"type" required:"true" cty:"type" hcl:"type"`
+	BlobStore     *string  `mapstructure:"blobStore" required:"false" cty:"blobStore" hcl:"blobStore"`
+	RemoteUrl     *string  `mapstructure:"remoteUrl" required:"false" cty:"remoteUrl" hcl:"remoteUrl"`
+	Members       []string `mapstructure:"members" required:"false" cty:"members" hcl:"members"`
+	VersionPolicy *string  `mapstructure:"versionPolicy" required:"false" cty:"versionPolicy" hcl:"versionPolicy"`
+	WritePolicy   *string  `mapstructure:"writePolicy" required:"false" cty:"writePolicy" hcl:"writePolicy"`
+}
+
+// FlatMapstructure returns a new FlatRepository.
+// FlatRepository is an auto-generated flat version of Repository.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Repository) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRepository)
+}
+
+// HCL2Spec returns the hcl spec of a Repository.
+// This spec is used by HCL to read the fields of Repository.
+// The decoded values from this spec will then be applied to a FlatRepository.
+func (*FlatRepository) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":          &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"format":        &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
+		"type":          &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"blobStore":     &hcldec.AttrSpec{Name: "blobStore", Type: cty.String, Required: false},
+		"remoteUrl":     &hcldec.AttrSpec{Name: "remoteUrl", Type: cty.String, Required: false},
+		"members":       &hcldec.AttrSpec{Name: "members", Type: cty.List(cty.String), Required: false},
+		"versionPolicy": &hcldec.AttrSpec{Name: "versionPolicy", Type: cty.String, Required: false},
+		"writePolicy":   &hcldec.AttrSpec{Name: "writePolicy", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatRole is an auto-generated flat version of Role.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRole struct {
+	Id          *string  `mapstructure:"id" required:"true" cty:"id" hcl:"id"`
+	Name        *string  `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Description *string  `mapstructure:"description" required:"false" cty:"description" hcl:"description"`
+	Privileges  []string `mapstructure:"privileges" required:"false" cty:"privileges" hcl:"privileges"`
+	Roles       []string `mapstructure:"roles" required:"false" cty:"roles" hcl:"roles"`
+}
+
+// FlatMapstructure returns a new FlatRole.
+// FlatRole is an auto-generated flat version of Role.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Role) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRole)
+}
+
+// HCL2Spec returns the hcl spec of a Role.
+// This spec is used by HCL to read the fields of Role.
+// The decoded values from this spec will then be applied to a FlatRole.
+func (*FlatRole) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":          &hcldec.AttrSpec{Name: "id", Type: cty.String, Required: false},
+		"name":        &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"description": &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
+		"privileges":  &hcldec.AttrSpec{Name: "privileges", Type: cty.List(cty.String), Required: false},
+		"roles":       &hcldec.AttrSpec{Name: "roles", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlatUser is an auto-generated flat version of User.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatUser struct {
+	UserId    *string  `mapstructure:"userId" required:"true" cty:"userId" hcl:"userId"`
+	FirstName *string  `mapstructure:"firstName" required:"true" cty:"firstName" hcl:"firstName"`
+	LastName  *string  `mapstructure:"lastName" required:"true" cty:"lastName" hcl:"lastName"`
+	Email     *string  `mapstructure:"email" required:"true" cty:"email" hcl:"email"`
+	Password  *string  `mapstructure:"password" required:"true" cty:"password" hcl:"password"`
+	Roles     []string `mapstructure:"roles" required:"true" cty:"roles" hcl:"roles"`
+}
+
+// FlatMapstructure returns a new FlatUser.
+// FlatUser is an auto-generated flat version of User.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*User) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatUser)
+}
+
+// HCL2Spec returns the hcl spec of a User.
+// This spec is used by HCL to read the fields of User.
+// The decoded values from this spec will then be applied to a FlatUser.
+func (*FlatUser) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"userId":    &hcldec.AttrSpec{Name: "userId", Type: cty.String, Required: false},
+		"firstName": &hcldec.AttrSpec{Name: "firstName", Type: cty.String, Required: false},
+		"lastName":  &hcldec.AttrSpec{Name: "lastName", Type: cty.String, Required: false},
+		"email":     &hcldec.AttrSpec{Name: "email", Type: cty.String, Required: false},
+		"password":  &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
+		"roles":     &hcldec.AttrSpec{Name: "roles", Type: cty.List(cty.String), Required: false},
 	}
 	return s
 }
@@ -0,0 +1,54 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package artifactory
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"strings"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	data := []struct {
+		name                 string
+		volumeExitStatus     int
+		expectVolumeCreation bool
+	}{
+		{"new data volume", 1, true},
+		{"existing data volume", 0, false},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			provisioner := new(Provisioner)
+			err := provisioner.Prepare(map[string]interface{}{
+				"sonatypeNexusRepositoryDomain": "nexus.mycompany.com",
+				"sslCertMode":                   "self-signed",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			communicator := communicatortest.New().On("docker volume inspect nexus-data", d.volumeExitStatus, "")
+			if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			volumeCreated := false
+			for _, script := range communicator.Scripts() {
+				volumeCreated = volumeCreated || strings.Contains(script, "docker volume create --name nexus-data")
+			}
+			if volumeCreated != d.expectVolumeCreation {
+				t.Errorf("Expected volume creation: %t, got: %t", d.expectVolumeCreation, volumeCreated)
+			}
+
+			nginxConfig := communicator.Uploaded("/home/ubuntu/nginx-ssl.conf")
+			if nginxConfig == nil || string(nginxConfig.Content) != getNginxConfig(provisioner.config) {
+				t.Errorf("Expected the generated Nginx config to be uploaded, got: %v", nginxConfig)
+			}
+		})
+	}
+}
@@ -22,8 +22,7 @@ build {
 
   provisioner "paion-data-sonatype-nexus-repository-provisioner" {
     homeDir                       = "/"
-    sslCertBase64                 = "YXNkZnNnaHRkeWhyZXJ3ZGZydGV3ZHNmZ3RoeTY0cmV3ZGZyZWd0cmV3d2ZyZw=="
-    sslCertKeyBase64              = "MzI0NXRnZjk4dmJoIGNsO2VbNDM1MHRdzszNDM1b2l0cmo="
+    sslCertMode                   = "self-signed"
     sonatypeNexusRepositoryDomain = "nexus.mycompany.com"
   }
 }
@@ -0,0 +1,241 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"text/template"
+)
+
+// CertModeAcme Certificate mode in which certbot is installed in remote machine and obtains a certificate for the
+// domain from an ACME directory, such as Let's Encrypt, during the build
+const CertModeAcme string = "acme"
+
+// DefaultAcmeDirectoryUrl The production directory of Let's Encrypt
+const DefaultAcmeDirectoryUrl string = "https://acme-v02.api.letsencrypt.org/directory"
+
+// AcmeWebroot The directory that the default Nginx server on port 80 serves, from which certbot answers HTTP-01
+// challenges
+const AcmeWebroot string = "/var/www/html"
+
+// AcmeConfigDir The default certbot config directory, under which obtained certificates are stored
+const AcmeConfigDir string = "/etc/letsencrypt"
+
+const acmeDirectoryCaFilename string = "acme-directory-ca.pem"
+const acmeDirectoryCaDst string = "/usr/local/share/ca-certificates/acme-directory-ca.pem"
+const acmeRenewalUnitName string = "certbot-renew"
+const systemdUnitDir string = "/etc/systemd/system"
+
+// ProvisionAcmeCertificate Installs certbot in remote machine, obtains a certificate for the domain from the configured
+// ACME directory and installs a systemd timer that renews the certificate twice a day.
+//
+// webroot: A directory served over HTTP on port 80 for the domain, from which the challenge is answered. If empty,
+// certbot answers the challenge with its own standalone server instead, which requires port 80 to be free
+//
+// configDir: The certbot config directory. The certificate and key are stored at "<configDir>/live/<domain>/" as
+// "fullchain.pem" and "privkey.pem"
+//
+// deployHook: A command that runs after each successful renewal, such as reloading a web server. Can be empty
+//
+// The renewal timer is a systemd unit, so distributions without systemd, such as Alpine, are not supported
+func ProvisionAcmeCertificate(
+	ctx context.Context,
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	d *distro.Distro,
+	homeDir string,
+	sslConfig Config,
+	domain string,
+	webroot string,
+	configDir string,
+	deployHook string,
+) error {
+	if !d.Systemd() {
+		return fmt.Errorf("'%s' mode requires systemd to renew certificates, which '%s' does not run", CertModeAcme, d.ID)
+	}
+
+	if sslConfig.SslAcmeDirectoryCaBase64 != "" {
+		ca, err := DecodeBase64(sslConfig.SslAcmeDirectoryCaBase64)
+		if err != nil {
+			return fmt.Errorf("sslAcmeDirectoryCaBase64: %s", err)
+		}
+		if err = UploadContent(interCtx, ui, communicator, ca, filepath.Join(homeDir, acmeDirectoryCaFilename)); err != nil {
+			return err
+		}
+	}
+
+	service, timer := getAcmeRenewalUnits(sslConfig, configDir, deployHook)
+	if err := UploadContent(interCtx, ui, communicator, service, filepath.Join(homeDir, acmeRenewalUnitName+".service")); err != nil {
+		return err
+	}
+	if err := UploadContent(interCtx, ui, communicator, timer, filepath.Join(homeDir, acmeRenewalUnitName+".timer")); err != nil {
+		return err
+	}
+
+	return shell.Provision(ctx, ui, communicator, getAcmeSteps(d, homeDir, sslConfig, domain, webroot, configDir))
+}
+
+func (c *Config) validateAcme() []error {
+	var errs []error
+
+	if c.SslAcmeEmail == "" {
+		errs = append(errs, fmt.Errorf("sslAcmeEmail is required in '%s' mode", CertModeAcme))
+	} else if addr, err := mail.ParseAddress(c.SslAcmeEmail); err != nil || addr.Address != c.SslAcmeEmail {
+		errs = append(errs, fmt.Errorf("sslAcmeEmail: '%s' is not a bare email address such as 'admin@mycompany.com'", c.SslAcmeEmail))
+	}
+
+	if directory, err := url.Parse(c.acmeDirectoryUrl()); err != nil || directory.Scheme != "https" || directory.Host == "" {
+		errs = append(errs, fmt.Errorf("sslAcmeDirectoryUrl: '%s' is not a valid HTTPS URL", c.SslAcmeDirectoryUrl))
+	}
+
+	if c.SslAcmeDirectoryCaBase64 != "" {
+		ca, err := DecodeBase64(c.SslAcmeDirectoryCaBase64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sslAcmeDirectoryCaBase64: %s", err))
+		} else if block, _ := pem.Decode([]byte(ca)); block == nil || block.Type != "CERTIFICATE" {
+			errs = append(errs, fmt.Errorf("sslAcmeDirectoryCaBase64: not a PEM-encoded certificate"))
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) acmeDirectoryUrl() string {
+	if c.SslAcmeDirectoryUrl == "" {
+		return DefaultAcmeDirectoryUrl
+	}
+
+	return c.SslAcmeDirectoryUrl
+}
+
+// Returns the environment certbot runs with, which makes it trust the CA of a private ACME directory, such as Pebble
+func (c *Config) acmeEnv() string {
+	if c.SslAcmeDirectoryCaBase64 == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("REQUESTS_CA_BUNDLE=%s", acmeDirectoryCaDst)
+}
+
+func getAcmeSteps(d *distro.Distro, homeDir string, sslConfig Config, domain string, webroot string, configDir string) []shell.Step {
+	return []shell.Step{
+		{
+			Name:     fmt.Sprintf("Obtaining SSL certificate for %s from %s", domain, sslConfig.acmeDirectoryUrl()),
+			Commands: getAcmeCommands(d, homeDir, sslConfig, domain, webroot, configDir),
+			Creates:  filepath.Join(configDir, "live", domain, "fullchain.pem"),
+		},
+		{
+			Name:     "Installing SSL certificate renewal timer",
+			Commands: getAcmeRenewalCommands(homeDir),
+		},
+	}
+}
+
+func getAcmeCommands(d *distro.Distro, homeDir string, sslConfig Config, domain string, webroot string, configDir string) []string {
+	commands := getCommandsInstallingCertbot(d)
+
+	if sslConfig.SslAcmeDirectoryCaBase64 != "" {
+		commands = append(commands, fmt.Sprintf("sudo mv %s/%s %s", homeDir, acmeDirectoryCaFilename, acmeDirectoryCaDst))
+	}
+
+	challenge := "--standalone"
+	if webroot != "" {
+		challenge = fmt.Sprintf("--webroot -w %s", webroot)
+	}
+
+	sudo := "sudo"
+	if env := sslConfig.acmeEnv(); env != "" {
+		sudo = fmt.Sprintf("sudo %s", env)
+	}
+
+	return append(
+		commands,
+		fmt.Sprintf(
+			"%s certbot certonly --non-interactive --agree-tos --email %s --server %s --config-dir %s %s -d %s",
+			sudo,
+			shell.Quote(sslConfig.SslAcmeEmail),
+			shell.Quote(sslConfig.acmeDirectoryUrl()),
+			configDir,
+			challenge,
+			domain,
+		),
+	)
+}
+
+// Amazon Linux ships no certbot package, so certbot is installed into a virtualenv as its docs recommend. RHEL-compatible
+// distributions ship it in EPEL
+func getCommandsInstallingCertbot(d *distro.Distro) []string {
+	switch {
+	case d.ID == "amzn":
+		return []string{
+			d.InstallCommand("python3", "augeas-libs"),
+			"sudo python3 -m venv /opt/certbot",
+			"sudo /opt/certbot/bin/pip install --upgrade pip certbot",
+			"sudo ln -sf /opt/certbot/bin/certbot /usr/bin/certbot",
+		}
+	case d.Is("rhel", "centos"):
+		return []string{d.InstallCommand("epel-release"), d.InstallCommand("certbot")}
+	default:
+		return []string{d.InstallCommand("certbot")}
+	}
+}
+
+func getAcmeRenewalCommands(homeDir string) []string {
+	return []string{
+		fmt.Sprintf("sudo mv %s/%s.service %s/", homeDir, acmeRenewalUnitName, systemdUnitDir),
+		fmt.Sprintf("sudo mv %s/%s.timer %s/", homeDir, acmeRenewalUnitName, systemdUnitDir),
+		"sudo systemctl daemon-reload",
+		fmt.Sprintf("sudo systemctl enable %s.timer", acmeRenewalUnitName),
+	}
+}
+
+func getAcmeRenewalUnits(sslConfig Config, configDir string, deployHook string) (string, string) {
+	var renewalConfigs = struct {
+		Env        string
+		ConfigDir  string
+		DeployHook string
+	}{sslConfig.acmeEnv(), configDir, deployHook}
+
+	var service bytes.Buffer
+	t := template.Must(template.New("Certbot Renewal Service").Parse(`[Unit]
+Description=Renew certificates obtained by certbot
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+{{- if .Env}}
+Environment={{.Env}}
+{{- end}}
+ExecStart=/usr/bin/certbot renew --non-interactive --config-dir {{.ConfigDir}}{{if .DeployHook}} --deploy-hook "{{.DeployHook}}"{{end}}
+`))
+	if err := t.Execute(&service, renewalConfigs); err != nil {
+		panic(err)
+	}
+
+	timer := `[Unit]
+Description=Renew certificates obtained by certbot twice a day
+
+[Timer]
+OnCalendar=*-*-* 00,12:00:00
+RandomizedDelaySec=1h
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`
+
+	return service.String(), timer
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ubuntu = &distro.Distro{ID: "ubuntu", IDLike: []string{"debian"}, PackageManager: distro.Apt}
+
+func Test_getAcmeCommands(t *testing.T) {
+	caCert, _ := generateTestCa(t)
+	config := Config{
+		SslCertMode:              CertModeAcme,
+		SslAcmeEmail:             "admin@mycompany.com",
+		SslAcmeDirectoryUrl:      "https://localhost:14000/dir",
+		SslAcmeDirectoryCaBase64: encode(caCert),
+	}
+
+	actualCommands := getAcmeCommands(ubuntu, "/home/ubuntu", config, "app.mycompany.com", AcmeWebroot, AcmeConfigDir)
+
+	expectedCommands := []string{
+		"sudo apt install -y certbot",
+		"sudo mv /home/ubuntu/acme-directory-ca.pem /usr/local/share/ca-certificates/acme-directory-ca.pem",
+		"sudo REQUESTS_CA_BUNDLE=/usr/local/share/ca-certificates/acme-directory-ca.pem certbot certonly --non-interactive --agree-tos --email 'admin@mycompany.com' --server 'https://localhost:14000/dir' --config-dir /etc/letsencrypt --webroot -w /var/www/html -d app.mycompany.com",
+	}
+
+	if !reflect.DeepEqual(expectedCommands, actualCommands) {
+		t.Errorf("Expected and actual commands do not match: %s\n\n%s", expectedCommands, actualCommands)
+	}
+}
+
+func Test_getAcmeCommandsStandalone(t *testing.T) {
+	config := Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin@mycompany.com"}
+
+	actualCommands := getAcmeCommands(ubuntu, "/", config, "mail.mycompany.com", "", "/docker-data/certbot/certs")
+
+	expectedCommand := "sudo certbot certonly --non-interactive --agree-tos --email 'admin@mycompany.com' --server 'https://acme-v02.api.letsencrypt.org/directory' --config-dir /docker-data/certbot/certs --standalone -d mail.mycompany.com"
+	if actualCommands[1] != expectedCommand {
+		t.Errorf("Expected '%s', got '%s'", expectedCommand, actualCommands[1])
+	}
+}
+
+func Test_getCommandsInstallingCertbot(t *testing.T) {
+	data := []struct {
+		name             string
+		distro           *distro.Distro
+		expectedCommands []string
+	}{
+		{"Ubuntu", ubuntu, []string{"sudo apt install -y certbot"}},
+		{
+			"Rocky Linux",
+			&distro.Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, PackageManager: distro.Dnf},
+			[]string{"sudo dnf install -y epel-release", "sudo dnf install -y certbot"},
+		},
+		{
+			"Amazon Linux",
+			&distro.Distro{ID: "amzn", IDLike: []string{"fedora"}, PackageManager: distro.Dnf},
+			[]string{
+				"sudo dnf install -y python3 augeas-libs",
+				"sudo python3 -m venv /opt/certbot",
+				"sudo /opt/certbot/bin/pip install --upgrade pip certbot",
+				"sudo ln -sf /opt/certbot/bin/certbot /usr/bin/certbot",
+			},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			actualCommands := getCommandsInstallingCertbot(d.distro)
+			if !reflect.DeepEqual(d.expectedCommands, actualCommands) {
+				t.Errorf("Expected and actual commands do not match: %s\n\n%s", d.expectedCommands, actualCommands)
+			}
+		})
+	}
+}
+
+func Test_getAcmeRenewalUnits(t *testing.T) {
+	service, _ := getAcmeRenewalUnits(Config{}, AcmeConfigDir, "systemctl reload nginx")
+
+	expectedService := `[Unit]
+Description=Renew certificates obtained by certbot
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/certbot renew --non-interactive --config-dir /etc/letsencrypt --deploy-hook "systemctl reload nginx"
+`
+
+	if service != expectedService {
+		t.Errorf("Expected and actual services do not match: %s\n\n%s", expectedService, service)
+	}
+}
+
+func TestValidateAcme(t *testing.T) {
+	leafCert, leafKey := generateTestKeyPair(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "localhost")
+
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"Let's Encrypt", Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin@mycompany.com"}, false},
+		{
+			"Pebble",
+			Config{
+				SslCertMode:              CertModeAcme,
+				SslAcmeEmail:             "admin@mycompany.com",
+				SslAcmeDirectoryUrl:      "https://pebble:14000/dir",
+				SslAcmeDirectoryCaBase64: encode(leafCert),
+			},
+			false,
+		},
+		{"missing email", Config{SslCertMode: CertModeAcme}, true},
+		{"invalid email", Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin"}, true},
+		{"email with display name", Config{SslCertMode: CertModeAcme, SslAcmeEmail: "Admin <admin@mycompany.com>"}, true},
+		{"non-HTTPS directory", Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin@mycompany.com", SslAcmeDirectoryUrl: "http://pebble:14000/dir"}, true},
+		{"directory CA not a certificate", Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin@mycompany.com", SslAcmeDirectoryCaBase64: encode(leafKey)}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.Validate("app.mycompany.com")
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestLoadKeyPairAcme(t *testing.T) {
+	config := Config{SslCertMode: CertModeAcme, SslAcmeEmail: "admin@mycompany.com"}
+
+	if _, err := config.LoadKeyPair("app.mycompany.com"); err == nil || !strings.Contains(err.Error(), "remote machine") {
+		t.Errorf("Expected key pair not to be available locally in ACME mode, got: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"strings"
+	"time"
+)
+
+// DefaultExpiryWindowDays Default number of days before expiry from which on a certificate is reported as expiring soon
+const DefaultExpiryWindowDays int = 30
+
+// CertModeProvided Certificate mode in which the certificate and its key are supplied through the Config
+const CertModeProvided string = "provided"
+
+// CertModeSelfSigned Certificate mode in which a certificate is generated for the domain during the build, either
+// self-signed or signed by a local CA supplied through the Config
+const CertModeSelfSigned string = "self-signed"
+
+// Config The SSL certificate settings shared by all provisioners that serve their app behind an SSL-enabled Nginx.
+//
+// Provisioners embed it in their own Config with `mapstructure:",squash"` so that its fields show up at the top level
+// of the provisioner's HCL block
+type Config struct {
+	SslCertMode                   string `mapstructure:"sslCertMode" required:"false"`
+	SslCertBase64                 string `mapstructure:"sslCertBase64" required:"false"`
+	SslCertKeyBase64              string `mapstructure:"sslCertKeyBase64" required:"false"`
+	SslCertFile                   string `mapstructure:"sslCertFile" required:"false"`
+	SslCertKeyFile                string `mapstructure:"sslCertKeyFile" required:"false"`
+	SslCertEnv                    string `mapstructure:"sslCertEnv" required:"false"`
+	SslCertKeyEnv                 string `mapstructure:"sslCertKeyEnv" required:"false"`
+	SslChainFile                  string `mapstructure:"sslChainFile" required:"false"`
+	SslCertExpiryWindowDays       int    `mapstructure:"sslCertExpiryWindowDays" required:"false"`
+	SslCertFailWithinExpiryWindow bool   `mapstructure:"sslCertFailWithinExpiryWindow" required:"false"`
+	SslCaCertBase64               string `mapstructure:"sslCaCertBase64" required:"false"`
+	SslCaKeyBase64                string `mapstructure:"sslCaKeyBase64" required:"false"`
+	SslSelfSignedValidityDays     int    `mapstructure:"sslSelfSignedValidityDays" required:"false"`
+	SslAcmeEmail                  string `mapstructure:"sslAcmeEmail" required:"false"`
+	SslAcmeDirectoryUrl           string `mapstructure:"sslAcmeDirectoryUrl" required:"false"`
+	SslAcmeDirectoryCaBase64      string `mapstructure:"sslAcmeDirectoryCaBase64" required:"false"`
+	NginxPackagesSource           string `mapstructure:"nginxPackagesSource" required:"false"`
+}
+
+// KeyPair A PEM-encoded SSL certificate and its private key that have been verified to belong together
+type KeyPair struct {
+	Cert string
+	Key  string
+	Leaf *x509.Certificate
+}
+
+// Validate Returns all problems with the configured certificate, such as a key not matching the certificate, the
+// certificate not covering the provided domain or the certificate being expired.
+//
+// An empty domain is skipped, because missing values are reported by validation.CheckRequired
+func (c *Config) Validate(domain string) []error {
+	switch c.certMode() {
+	case CertModeProvided:
+		return c.validateProvided(domain)
+	case CertModeSelfSigned:
+		return c.validateSelfSigned()
+	case CertModeAcme:
+		return c.validateAcme()
+	default:
+		return []error{fmt.Errorf(
+			"sslCertMode: unsupported mode '%s', expected one of '%s', '%s' or '%s'",
+			c.SslCertMode,
+			CertModeProvided,
+			CertModeSelfSigned,
+			CertModeAcme,
+		)}
+	}
+}
+
+// LoadKeyPair Returns the certificate and key to serve the provided domain with, according to the sslCertMode.
+//
+// In "provided" mode, the certificate and key are read from whichever of the base64, file or environment variable fields
+// is configured, intermediates from sslChainFile are appended to the certificate, and it is verified that
+//
+//  1. the private key matches the public key of the certificate,
+//  2. the certificate covers the provided domain, and
+//  3. the certificate is currently valid
+//
+// In "self-signed" mode, a new certificate is generated for the domain. In "acme" mode, the certificate is obtained in
+// remote machine by ProvisionAcmeCertificate instead, so an error is returned
+//
+// domain: The domain that will be served with the certificate, for example "app.mycompany.com"
+//
+// Returns:
+// The key pair, or an error describing the first failed verification
+func (c *Config) LoadKeyPair(domain string) (*KeyPair, error) {
+	switch c.certMode() {
+	case CertModeSelfSigned:
+		return c.generateKeyPair(domain, time.Now())
+	case CertModeAcme:
+		return nil, fmt.Errorf("SSL certificate for '%s' is obtained in remote machine in '%s' mode", domain, CertModeAcme)
+	}
+
+	cert, err := c.loadCertPem()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.loadKeyPem()
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyKeyPair(cert, key, domain, time.Now())
+}
+
+// WarnIfExpiringSoon Tells the user if the certificate of a key pair expires within the configured expiry window. When
+// sslCertFailWithinExpiryWindow is set, Prepare has already rejected such certificate so this is a no-op
+func (c *Config) WarnIfExpiringSoon(ui packersdk.Ui, keyPair *KeyPair) {
+	if keyPair.expiresWithin(c.expiryWindow(), time.Now()) {
+		ui.Error(fmt.Sprintf(
+			"Warning: SSL certificate for %s expires on %s, which is within %d days",
+			keyPair.Leaf.DNSNames,
+			keyPair.Leaf.NotAfter.Format(time.RFC3339),
+			c.expiryWindowDays(),
+		))
+	}
+}
+
+// SensitiveValues Returns the private key material of the Config and of a key pair loaded from it, which is to be passed
+// to shell.Provision as sensitive so that it never shows up in Packer output. Each line of the PEM-encoded key is
+// included on its own, because remote output is streamed line by line
+func (c *Config) SensitiveValues(keyPair *KeyPair) []string {
+	sensitive := []string{c.SslCertKeyBase64, c.SslCaKeyBase64}
+	if keyPair == nil {
+		return sensitive
+	}
+
+	sensitive = append(sensitive, keyPair.Key)
+	for _, line := range strings.Split(keyPair.Key, "\n") {
+		if line != "" && !strings.HasPrefix(line, "-----") {
+			sensitive = append(sensitive, line)
+		}
+	}
+
+	return sensitive
+}
+
+// CheckOffline Returns an error if a provisioner runs in offline mode with a certificate mode that needs access to the
+// internet during the build, which "acme" does, or without the Nginx package files of nginxPackagesSource. A given
+// nginxPackagesSource must exist in either mode
+func (c *Config) CheckOffline(offline bool) error {
+	if offline && c.IsAcme() {
+		return fmt.Errorf("sslCertMode '%s' obtains the certificate over the internet and cannot be used in offline mode", CertModeAcme)
+	}
+
+	return validation.CheckOfflineSource(offline, "nginxPackagesSource", c.NginxPackagesSource)
+}
+
+// IsAcme Returns whether the certificate is obtained in remote machine by ProvisionAcmeCertificate rather than being
+// loaded by LoadKeyPair
+func (c *Config) IsAcme() bool {
+	return c.certMode() == CertModeAcme
+}
+
+func (c *Config) certMode() string {
+	if c.SslCertMode == "" {
+		return CertModeProvided
+	}
+
+	return c.SslCertMode
+}
+
+func (c *Config) validateProvided(domain string) []error {
+	var errs []error
+	if _, err := c.loadCertPem(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.loadKeyPem(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	keyPair, err := c.LoadKeyPair(domain)
+	if err != nil {
+		return []error{err}
+	}
+
+	if c.SslCertFailWithinExpiryWindow && keyPair.expiresWithin(c.expiryWindow(), time.Now()) {
+		return []error{fmt.Errorf("SSL certificate for '%s' expires on %s, which is within the %d-day expiry window", domain, keyPair.Leaf.NotAfter.Format(time.RFC3339), c.expiryWindowDays())}
+	}
+
+	return nil
+}
+
+func (c *Config) expiryWindowDays() int {
+	if c.SslCertExpiryWindowDays <= 0 {
+		return DefaultExpiryWindowDays
+	}
+
+	return c.SslCertExpiryWindowDays
+}
+
+func (c *Config) expiryWindow() time.Duration {
+	return time.Duration(c.expiryWindowDays()) * 24 * time.Hour
+}
+
+func (k *KeyPair) expiresWithin(window time.Duration, now time.Time) bool {
+	return now.Add(window).After(k.Leaf.NotAfter)
+}
+
+func verifyKeyPair(cert string, key string, domain string, now time.Time) (*KeyPair, error) {
+	tlsCert, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSL certificate/key pair: %s", err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing SSL certificate: %s", err)
+	}
+
+	if domain != "" {
+		if err := leaf.VerifyHostname(domain); err != nil {
+			return nil, fmt.Errorf("SSL certificate does not cover '%s': %s", domain, err)
+		}
+	}
+
+	if now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("SSL certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(leaf.NotBefore) {
+		return nil, fmt.Errorf("SSL certificate is not valid until %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+
+	return &KeyPair{Cert: cert, Key: key, Leaf: leaf}, nil
+}
@@ -10,56 +10,126 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/tmp"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"os"
 	"path/filepath"
 )
 
 const defaultHomeDir string = "/home/ubuntu"
-const nginxConfigDst string = "/etc/nginx/sites-enabled/default"
+const nginxPackagesDir string = "nginx-packages"
 const nginxConfigFilename string = "nginx-ssl.conf"
 const sslCertFilename string = "ssl.crt"
 const sslCertKeyFilename string = "ssl.key"
 
+// The rendered content of a certificate and key generated during the build
+const generatedCertPlaceholder string = "<self-signed certificate generated during the build>\n"
+const generatedKeyPlaceholder string = "<private key generated during the build>\n"
+
 const SslCertDst string = "/etc/ssl/certs/server.crt"
 const SslCertKeyDst string = "/etc/ssl/private/server.key"
 
+// Provision Verifies the configured SSL certificate against the provided domain, uploads it together with an optional
+// Nginx config, and then installs Nginx to serve with them. In "acme" mode, the certificate is obtained by certbot in
+// remote machine instead
+//
+// d: The distribution of remote machine, which determines how Nginx is installed and where its config is loaded.
+// Nginx is installed from the package files of nginxPackagesSource without access to the internet, if they are given
 func Provision(
 	ctx context.Context,
 	interCtx interpolate.Context,
 	ui packersdk.Ui,
 	communicator packersdk.Communicator,
+	d *distro.Distro,
 	homeDir string,
-	sslCertBase64 string,
-	sslCertKeyBase64 string,
+	sslConfig Config,
+	domain string,
 	nginxConfig string,
 ) error {
-	sslCert, err := DecodeBase64(sslCertBase64)
-	sslCertSource, err := WriteToFile(sslCert)
-	sslCertDestination := fmt.Sprintf(filepath.Join(homeDir, sslCertFilename))
-	err = file.Provision(interCtx, ui, communicator, sslCertSource, sslCertDestination)
+	if sslConfig.IsAcme() {
+		return provisionWithAcme(ctx, interCtx, ui, communicator, d, homeDir, sslConfig, domain, nginxConfig)
+	}
+
+	keyPair, err := sslConfig.LoadKeyPair(domain)
 	if err != nil {
-		return fmt.Errorf("error uploading '%s' to '%s': %s", sslCertSource, sslCertDestination, err)
+		return err
 	}
+	sslConfig.WarnIfExpiringSoon(ui, keyPair)
 
-	sslCertKey, err := DecodeBase64(sslCertKeyBase64)
-	sslCertKeySource, err := WriteToFile(sslCertKey)
+	sslCertDestination := fmt.Sprintf(filepath.Join(homeDir, sslCertFilename))
 	sslCertKeyDestination := fmt.Sprintf(filepath.Join(homeDir, sslCertKeyFilename))
-	err = file.Provision(interCtx, ui, communicator, sslCertKeySource, sslCertKeyDestination)
+	err = sslConfig.UploadKeyPair(interCtx, ui, communicator, keyPair, sslCertDestination, sslCertKeyDestination)
 	if err != nil {
-		return fmt.Errorf("error uploading '%s' to '%s': %s", sslCertKeySource, sslCertKeyDestination, err)
+		return err
 	}
 
 	if nginxConfig != "" {
-		nginxSource, err := WriteToFile(nginxConfig)
 		nginxDst := fmt.Sprintf(filepath.Join(homeDir, nginxConfigFilename))
-		err = file.Provision(interCtx, ui, communicator, nginxSource, nginxDst)
-		if err != nil {
-			return fmt.Errorf("error uploading '%s' to '%s': %s", nginxSource, nginxDst, err)
+		if err = UploadContent(interCtx, ui, communicator, nginxConfig, nginxDst); err != nil {
+			return err
 		}
 	}
 
-	return shell.Provision(ctx, ui, communicator, getSslSetupCommands(homeDir))
+	stepInstallingNginx, err := provisionNginxPackages(interCtx, ui, communicator, d, homeDir, sslConfig)
+	if err != nil {
+		return err
+	}
+
+	return shell.Provision(ctx, ui, communicator, getSslSetupSteps(d, homeDir, stepInstallingNginx), sslConfig.SensitiveValues(keyPair)...)
+}
+
+// Installs Nginx first so that its default server answers the ACME challenge of certbot, then points the Nginx config to
+// the obtained certificate, which is renewed with an Nginx reload afterwards
+func provisionWithAcme(
+	ctx context.Context,
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	d *distro.Distro,
+	homeDir string,
+	sslConfig Config,
+	domain string,
+	nginxConfig string,
+) error {
+	if nginxConfig != "" {
+		nginxDst := fmt.Sprintf(filepath.Join(homeDir, nginxConfigFilename))
+		if err := UploadContent(interCtx, ui, communicator, nginxConfig, nginxDst); err != nil {
+			return err
+		}
+	}
+
+	stepInstallingNginx, err := provisionNginxPackages(interCtx, ui, communicator, d, homeDir, sslConfig)
+	if err != nil {
+		return err
+	}
+	if err = shell.Provision(ctx, ui, communicator, []shell.Step{stepInstallingNginx}); err != nil {
+		return err
+	}
+
+	err = ProvisionAcmeCertificate(
+		ctx,
+		interCtx,
+		ui,
+		communicator,
+		d,
+		homeDir,
+		sslConfig,
+		domain,
+		AcmeWebroot,
+		AcmeConfigDir,
+		"systemctl reload nginx",
+	)
+	if err != nil {
+		return err
+	}
+
+	return shell.Provision(
+		ctx,
+		ui,
+		communicator,
+		[]shell.Step{{Name: "Loading SSL certificate and Nginx config", Commands: getAcmeSslSetupCommands(d, homeDir, domain)}},
+	)
 }
 
 // GetHomeDir Returns the home directory in Packer image builder. If a directory is specified, it is returned as it;
@@ -77,7 +147,9 @@ func GetHomeDir(configValue string) string {
 	return configValue
 }
 
-// WriteToFile Flushes a specified string into a temporary file and returns the path of that file.
+// WriteToFile Flushes a specified string into a temporary file, which is readable and writable by the owner only, and
+// returns the path of that file. The caller is responsible for removing the file, since the content can be sensitive,
+// such as a private key
 //
 // content: The provided file content
 //
@@ -89,7 +161,12 @@ func WriteToFile(content string) (string, error) {
 		return "", err
 	}
 	defer file.Close()
-	if _, err := file.WriteString(content); err != nil {
+
+	if err = file.Chmod(0600); err == nil {
+		_, err = file.WriteString(content)
+	}
+	if err != nil {
+		os.Remove(file.Name())
 		return "", err
 	}
 
@@ -100,20 +177,142 @@ func WriteToFile(content string) (string, error) {
 func DecodeBase64(encoded string) (string, error) {
 	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return "", fmt.Errorf("error interpolating destination: %s", err)
+		return "", fmt.Errorf("error decoding base64 string: %s", err)
 	}
 	return string(data), nil
 }
 
-// Return all commmnds for installing Nginx and loading SSL & Nginx config files to the proper location in remote
-// machine
-func getSslSetupCommands(homeDir string) []string {
+// UploadContent Flushes content into a temporary file and uploads that file to the specified destination in remote
+// machine. The temporary file is removed afterwards
+func UploadContent(
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	content string,
+	destination string,
+) error {
+	source, err := WriteToFile(content)
+	if err != nil {
+		return fmt.Errorf("error writing content for '%s' into a temporary file: %s", destination, err)
+	}
+	defer os.Remove(source)
+
+	if err = file.Provision(interCtx, ui, communicator, source, destination); err != nil {
+		return fmt.Errorf("error uploading '%s' to '%s': %s", source, destination, err)
+	}
+
+	return nil
+}
+
+// UploadKeyPair Uploads the certificate and key of a key pair loaded by LoadKeyPair to certDst and keyDst in remote
+// machine. If the communicator is a shell.Renderer, a key pair generated in "self-signed" mode is rendered as
+// placeholders instead, because it differs from run to run and would keep the rendered output from being diffed
+func (c *Config) UploadKeyPair(
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	keyPair *KeyPair,
+	certDst string,
+	keyDst string,
+) error {
+	cert, key := keyPair.Cert, keyPair.Key
+	if _, rendering := communicator.(shell.Renderer); rendering && c.certMode() == CertModeSelfSigned {
+		cert, key = generatedCertPlaceholder, generatedKeyPlaceholder
+	}
+
+	if err := UploadContent(interCtx, ui, communicator, cert, certDst); err != nil {
+		return err
+	}
+
+	return UploadContent(interCtx, ui, communicator, key, keyDst)
+}
+
+// Return all steps for installing Nginx and loading SSL & Nginx config files to the proper location in remote machine
+func getSslSetupSteps(d *distro.Distro, homeDir string, stepInstallingNginx shell.Step) []shell.Step {
+	return []shell.Step{
+		stepInstallingNginx,
+		{
+			Name: "Loading SSL certificate and Nginx config",
+			Commands: []string{
+				fmt.Sprintf("sudo mv %s/%s %s", homeDir, nginxConfigFilename, getNginxConfigDst(d)),
+				fmt.Sprintf("sudo mv %s/%s %s", homeDir, sslCertFilename, SslCertDst),
+				fmt.Sprintf("sudo mv %s/%s %s", homeDir, sslCertKeyFilename, SslCertKeyDst),
+			},
+		},
+	}
+}
+
+// Return all commands for loading the Nginx config and linking the certificate obtained by certbot to the location
+// that the Nginx config refers to
+func getAcmeSslSetupCommands(d *distro.Distro, homeDir string, domain string) []string {
+	liveDir := filepath.Join(AcmeConfigDir, "live", domain)
+
 	return []string{
-		"sudo apt update && sudo apt upgrade -y",
+		fmt.Sprintf("sudo mv %s/%s %s", homeDir, nginxConfigFilename, getNginxConfigDst(d)),
+		fmt.Sprintf("sudo ln -sf %s/fullchain.pem %s", liveDir, SslCertDst),
+		fmt.Sprintf("sudo ln -sf %s/privkey.pem %s", liveDir, SslCertKeyDst),
+	}
+}
+
+// Returns the location of the Nginx config that replaces the default server of the Nginx package of a distribution
+func getNginxConfigDst(d *distro.Distro) string {
+	switch d.PackageManager {
+	case distro.Apt:
+		return "/etc/nginx/sites-enabled/default"
+	case distro.Apk:
+		return "/etc/nginx/http.d/default.conf"
+	default:
+		return "/etc/nginx/conf.d/default.conf"
+	}
+}
 
-		"sudo apt install -y nginx",
-		fmt.Sprintf("sudo mv %s/%s %s", homeDir, nginxConfigFilename, nginxConfigDst),
-		fmt.Sprintf("sudo mv %s/%s %s", homeDir, sslCertFilename, SslCertDst),
-		fmt.Sprintf("sudo mv %s/%s %s", homeDir, sslCertKeyFilename, SslCertKeyDst),
+// Uploads the Nginx package files of nginxPackagesSource, if given, to "<homeDir>/nginx-packages" and returns the step
+// that installs Nginx from them. Otherwise, the returned step installs Nginx over the internet
+func provisionNginxPackages(
+	interCtx interpolate.Context,
+	ui packersdk.Ui,
+	communicator packersdk.Communicator,
+	d *distro.Distro,
+	homeDir string,
+	sslConfig Config,
+) (shell.Step, error) {
+	if sslConfig.NginxPackagesSource == "" {
+		return getStepInstallingNginx(d, ""), nil
 	}
+
+	packagesDir := filepath.Join(homeDir, nginxPackagesDir)
+	if err := file.ProvisionDirContents(interCtx, ui, communicator, sslConfig.NginxPackagesSource, packagesDir); err != nil {
+		return shell.Step{}, err
+	}
+
+	return getStepInstallingNginx(d, packagesDir), nil
+}
+
+// Installs Nginx, either over the internet or, if packagesDir is given, from the package files in that directory of
+// remote machine, in which case the system packages are not upgraded either. Other than Debian's, Nginx packages
+// neither start Nginx, nor create the directories that the provisioned Nginx configs refer to, and RHEL-compatible ones
+// declare a default server in nginx.conf, which conflicts with the one in provisioned configs. SELinux, where enforced,
+// is told to allow Nginx to proxy to local apps
+func getStepInstallingNginx(d *distro.Distro, packagesDir string) shell.Step {
+	name := "Installing Nginx"
+	commands := []string{
+		d.UpgradeCommand(),
+		d.InstallCommand("nginx"),
+	}
+	if packagesDir != "" {
+		name = "Installing Nginx from packages"
+		commands = []string{d.InstallLocalCommand(packagesDir)}
+	}
+
+	if d.PackageManager != distro.Apt {
+		commands = append(
+			commands,
+			fmt.Sprintf("sudo mkdir -p %s %s", AcmeWebroot, filepath.Dir(SslCertKeyDst)),
+			"sudo sed -i 's/ default_server//' /etc/nginx/nginx.conf",
+			"if command -v setsebool > /dev/null; then sudo setsebool -P httpd_can_network_connect 1; fi",
+			d.ServiceEnableCommand("nginx"),
+		)
+	}
+
+	return shell.Step{Name: name, Commands: commands}
 }
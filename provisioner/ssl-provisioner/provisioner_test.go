@@ -3,7 +3,28 @@
 
 package ssl
 
-import "testing"
+import (
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A fake machine that remembers the local files uploaded to it
+type recordingCommunicator struct {
+	*communicatortest.Communicator
+	sources []string
+}
+
+func (c *recordingCommunicator) Upload(path string, input io.Reader, fi *os.FileInfo) error {
+	c.sources = append(c.sources, (*fi).Name())
+	return c.Communicator.Upload(path, input, fi)
+}
 
 func TestWriteToFile(t *testing.T) {
 	filename1, err := WriteToFile("foo")
@@ -16,10 +37,86 @@ func TestWriteToFile(t *testing.T) {
 		t.Error(err)
 	}
 
+	defer os.Remove(filename1)
+	defer os.Remove(filename2)
+
 	t.Logf("filename 1: %s; filename 2: %s", filename1, filename2)
 	if filename1 == filename2 {
 		t.Errorf("WritingToFile on the same content should generate different file names across invocations. But 2 function calls generate a same filename of %s", filename1)
 	}
+
+	if info, err := os.Stat(filename1); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected temporary file readable by owner only, got: %v, %v", info, err)
+	}
+}
+
+func TestUploadContent(t *testing.T) {
+	communicator := &recordingCommunicator{Communicator: communicatortest.New()}
+
+	err := UploadContent(interpolate.Context{}, packersdk.TestUi(t), communicator, "private key", "/home/ubuntu/ssl.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded := communicator.Uploaded("/home/ubuntu/ssl.key")
+	if uploaded == nil || string(uploaded.Content) != "private key" {
+		t.Errorf("Expected content to be uploaded, got: %v", uploaded)
+	}
+
+	if len(communicator.sources) != 1 {
+		t.Fatalf("Expected one upload, got: %s", communicator.sources)
+	}
+	if _, err = os.Stat(filepath.Join(os.TempDir(), communicator.sources[0])); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary file '%s' to be removed after upload, got: %v", communicator.sources[0], err)
+	}
+}
+
+// A fake renderer that records uploads only
+type renderingCommunicator struct {
+	*communicatortest.Communicator
+}
+
+func (c *renderingCommunicator) RenderStep(name string, guards []string, script string) error {
+	return nil
+}
+
+func TestUploadKeyPair(t *testing.T) {
+	keyPair := &KeyPair{Cert: "certificate", Key: "private key"}
+
+	data := []struct {
+		name         string
+		config       Config
+		rendering    bool
+		expectedCert string
+		expectedKey  string
+	}{
+		{"provided", Config{}, false, "certificate", "private key"},
+		{"provided rendered", Config{}, true, "certificate", "private key"},
+		{"self-signed", Config{SslCertMode: CertModeSelfSigned}, false, "certificate", "private key"},
+		{"self-signed rendered", Config{SslCertMode: CertModeSelfSigned}, true, generatedCertPlaceholder, generatedKeyPlaceholder},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			fake := communicatortest.New()
+			var communicator packersdk.Communicator = fake
+			if d.rendering {
+				communicator = &renderingCommunicator{fake}
+			}
+
+			err := d.config.UploadKeyPair(interpolate.Context{}, packersdk.TestUi(t), communicator, keyPair, "/home/ubuntu/ssl.crt", "/home/ubuntu/ssl.key")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cert := fake.Uploaded("/home/ubuntu/ssl.crt"); cert == nil || string(cert.Content) != d.expectedCert {
+				t.Errorf("Expected certificate '%s', got: %v", d.expectedCert, cert)
+			}
+			if key := fake.Uploaded("/home/ubuntu/ssl.key"); key == nil || string(key.Content) != d.expectedKey {
+				t.Errorf("Expected key '%s', got: %v", d.expectedKey, key)
+			}
+		})
+	}
 }
 
 func TestGetHomeDir(t *testing.T) {
@@ -41,3 +138,51 @@ func TestGetHomeDir(t *testing.T) {
 		})
 	}
 }
+
+func Test_getStepInstallingNginx(t *testing.T) {
+	rocky := &distro.Distro{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, PackageManager: distro.Dnf}
+
+	data := []struct {
+		name             string
+		distro           *distro.Distro
+		packagesDir      string
+		expectedCommands []string
+	}{
+		{"Ubuntu", ubuntu, "", []string{"sudo apt update && sudo apt upgrade -y", "sudo apt install -y nginx"}},
+		{"Ubuntu from packages", ubuntu, "/home/ubuntu/nginx-packages", []string{"sudo apt install -y /home/ubuntu/nginx-packages/*.deb"}},
+		{
+			"Rocky Linux",
+			rocky,
+			"",
+			[]string{
+				"sudo dnf update -y",
+				"sudo dnf install -y nginx",
+				"sudo mkdir -p /var/www/html /etc/ssl/private",
+				"sudo sed -i 's/ default_server//' /etc/nginx/nginx.conf",
+				"if command -v setsebool > /dev/null; then sudo setsebool -P httpd_can_network_connect 1; fi",
+				"sudo systemctl enable --now nginx",
+			},
+		},
+		{
+			"Rocky Linux from packages",
+			rocky,
+			"/home/rocky/nginx-packages",
+			[]string{
+				"sudo dnf install -y /home/rocky/nginx-packages/*.rpm",
+				"sudo mkdir -p /var/www/html /etc/ssl/private",
+				"sudo sed -i 's/ default_server//' /etc/nginx/nginx.conf",
+				"if command -v setsebool > /dev/null; then sudo setsebool -P httpd_can_network_connect 1; fi",
+				"sudo systemctl enable --now nginx",
+			},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			actualCommands := getStepInstallingNginx(d.distro, d.packagesDir).Commands
+			if !reflect.DeepEqual(d.expectedCommands, actualCommands) {
+				t.Errorf("Expected and actual commands do not match: %s\n\n%s", d.expectedCommands, actualCommands)
+			}
+		})
+	}
+}
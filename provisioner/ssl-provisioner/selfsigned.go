@@ -0,0 +1,147 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DefaultSelfSignedValidityDays Default number of days a generated certificate stays valid
+const DefaultSelfSignedValidityDays int = 365
+
+// localCa A CA certificate and the private key that signs certificates generated in "self-signed" mode
+type localCa struct {
+	cert    *x509.Certificate
+	certPem string
+	key     crypto.Signer
+}
+
+func (c *Config) validateSelfSigned() []error {
+	var errs []error
+	if c.SslSelfSignedValidityDays < 0 {
+		errs = append(errs, fmt.Errorf("sslSelfSignedValidityDays must not be negative"))
+	}
+
+	if (c.SslCaCertBase64 == "") != (c.SslCaKeyBase64 == "") {
+		return append(errs, fmt.Errorf("sslCaCertBase64 and sslCaKeyBase64 must be specified together"))
+	}
+
+	if _, err := c.loadCa(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func (c *Config) selfSignedValidityDays() int {
+	if c.SslSelfSignedValidityDays == 0 {
+		return DefaultSelfSignedValidityDays
+	}
+
+	return c.SslSelfSignedValidityDays
+}
+
+// Returns the configured local CA or nil if certificates are to be self-signed
+func (c *Config) loadCa() (*localCa, error) {
+	if c.SslCaCertBase64 == "" || c.SslCaKeyBase64 == "" {
+		return nil, nil
+	}
+
+	caCert, err := DecodeBase64(c.SslCaCertBase64)
+	if err != nil {
+		return nil, fmt.Errorf("sslCaCertBase64: %s", err)
+	}
+
+	caKey, err := DecodeBase64(c.SslCaKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("sslCaKeyBase64: %s", err)
+	}
+
+	tlsCert, err := tls.X509KeyPair([]byte(caCert), []byte(caKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate/key pair: %s", err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CA certificate: %s", err)
+	}
+	if !leaf.IsCA {
+		return nil, fmt.Errorf("sslCaCertBase64: certificate '%s' is not a CA certificate", leaf.Subject)
+	}
+
+	signer, ok := tlsCert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("sslCaKeyBase64: unsupported private key type %T", tlsCert.PrivateKey)
+	}
+
+	return &localCa{cert: leaf, certPem: caCert, key: signer}, nil
+}
+
+// Generates a new key and certificate for the domain. The certificate is signed by the configured local CA if there is
+// one; otherwise it is self-signed. When signed by a CA, the returned certificate is the full chain of leaf followed by
+// the CA certificate
+func (c *Config) generateKeyPair(domain string, now time.Time) (*KeyPair, error) {
+	ca, err := c.loadCa()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating SSL certificate key: %s", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating SSL certificate serial number: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: domain},
+		DNSNames:              []string{domain},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Duration(c.selfSignedValidityDays()) * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	parent, signer, chain := template, crypto.Signer(key), ""
+	if ca != nil {
+		parent, signer, chain = ca.cert, ca.key, ca.certPem
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("error generating SSL certificate for '%s': %s", domain, err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated SSL certificate: %s", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding generated SSL certificate key: %s", err)
+	}
+
+	return &KeyPair{
+		Cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})) + chain,
+		Key:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+		Leaf: leaf,
+	}, nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func generateTestCa(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "My Company Local CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestLoadKeyPairSelfSigned(t *testing.T) {
+	config := Config{SslCertMode: CertModeSelfSigned, SslSelfSignedValidityDays: 7}
+
+	keyPair, err := config.LoadKeyPair("app.mycompany.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifyKeyPair(keyPair.Cert, keyPair.Key, "app.mycompany.com", time.Now()); err != nil {
+		t.Errorf("Generated key pair does not verify: %s", err)
+	}
+	leaf := keyPair.Leaf
+	if err := leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature); err != nil {
+		t.Errorf("Generated certificate is not self-signed: %s", err)
+	}
+	if expiry := time.Until(keyPair.Leaf.NotAfter); expiry > 7*24*time.Hour || expiry < 6*24*time.Hour {
+		t.Errorf("Expected generated certificate to expire in 7 days, got %s", keyPair.Leaf.NotAfter)
+	}
+}
+
+func TestLoadKeyPairSignedByLocalCa(t *testing.T) {
+	caCert, caKey := generateTestCa(t)
+	config := Config{SslCertMode: CertModeSelfSigned, SslCaCertBase64: encode(caCert), SslCaKeyBase64: encode(caKey)}
+
+	keyPair, err := config.LoadKeyPair("app.mycompany.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(caCert))
+	if _, err := keyPair.Leaf.Verify(x509.VerifyOptions{DNSName: "app.mycompany.com", Roots: roots}); err != nil {
+		t.Errorf("Generated certificate is not signed by local CA: %s", err)
+	}
+
+	if block, rest := pem.Decode([]byte(keyPair.Cert)); block == nil || string(rest) != caCert {
+		t.Errorf("Expected certificate to be followed by CA certificate in the chain, got:\n%s", keyPair.Cert)
+	}
+}
+
+func TestValidateSelfSigned(t *testing.T) {
+	caCert, caKey := generateTestCa(t)
+	leafCert, leafKey := generateTestKeyPair(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "app.mycompany.com")
+
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"self-signed", Config{SslCertMode: CertModeSelfSigned}, false},
+		{"local CA", Config{SslCertMode: CertModeSelfSigned, SslCaCertBase64: encode(caCert), SslCaKeyBase64: encode(caKey)}, false},
+		{"CA certificate without key", Config{SslCertMode: CertModeSelfSigned, SslCaCertBase64: encode(caCert)}, true},
+		{"non-CA certificate", Config{SslCertMode: CertModeSelfSigned, SslCaCertBase64: encode(leafCert), SslCaKeyBase64: encode(leafKey)}, true},
+		{"negative validity", Config{SslCertMode: CertModeSelfSigned, SslSelfSignedValidityDays: -1}, true},
+		{"unknown mode", Config{SslCertMode: "foo"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.Validate("app.mycompany.com")
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
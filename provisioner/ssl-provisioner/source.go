@@ -0,0 +1,135 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pemSource One of the alternative Config fields from which a PEM-encoded certificate or key is read in "provided" mode
+type pemSource struct {
+	field string
+	value string
+	read  func(value string) (string, error)
+}
+
+// Returns the PEM-encoded certificate, given by exactly one of sslCertBase64, sslCertFile or sslCertEnv, followed by
+// the intermediates in sslChainFile, if any
+func (c *Config) loadCertPem() (string, error) {
+	cert, err := loadPem("certificate", []pemSource{
+		{"sslCertBase64", c.SslCertBase64, DecodeBase64},
+		{"sslCertFile", c.SslCertFile, readFile},
+		{"sslCertEnv", c.SslCertEnv, readEnv},
+	})
+	if err != nil || c.SslChainFile == "" {
+		return cert, err
+	}
+
+	chain, err := readFile(c.SslChainFile)
+	if err != nil {
+		return "", fmt.Errorf("sslChainFile: %s", err)
+	}
+
+	return assembleChain(cert, chain)
+}
+
+// Returns the PEM-encoded key, given by exactly one of sslCertKeyBase64, sslCertKeyFile or sslCertKeyEnv
+func (c *Config) loadKeyPem() (string, error) {
+	return loadPem("certificate key", []pemSource{
+		{"sslCertKeyBase64", c.SslCertKeyBase64, DecodeBase64},
+		{"sslCertKeyFile", c.SslCertKeyFile, readFile},
+		{"sslCertKeyEnv", c.SslCertKeyEnv, readEnv},
+	})
+}
+
+func loadPem(name string, sources []pemSource) (string, error) {
+	var specified []pemSource
+	var fields []string
+	for _, source := range sources {
+		fields = append(fields, source.field)
+		if source.value != "" {
+			specified = append(specified, source)
+		}
+	}
+
+	switch len(specified) {
+	case 0:
+		return "", fmt.Errorf("SSL %s is required in '%s' mode; specify one of %s", name, CertModeProvided, strings.Join(fields, ", "))
+	case 1:
+		content, err := specified[0].read(specified[0].value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", specified[0].field, err)
+		}
+		return content, nil
+	default:
+		var conflicts []string
+		for _, source := range specified {
+			conflicts = append(conflicts, source.field)
+		}
+		return "", fmt.Errorf("SSL %s is specified more than once; %s are mutually exclusive", name, strings.Join(conflicts, " and "))
+	}
+}
+
+func readFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func readEnv(name string) (string, error) {
+	content, ok := os.LookupEnv(name)
+	if !ok || content == "" {
+		return "", fmt.Errorf("environment variable '%s' is not set", name)
+	}
+
+	return content, nil
+}
+
+// Appends the intermediate certificates of a chain to a leaf certificate, verifying that each certificate is signed by
+// the one following it
+func assembleChain(leaf string, chain string) (string, error) {
+	var certs []*x509.Certificate
+	var fullChain strings.Builder
+	for rest := []byte(leaf + "\n" + chain); ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("error parsing SSL certificate chain: %s", err)
+		}
+		certs = append(certs, cert)
+		fullChain.Write(pem.EncodeToMemory(block))
+	}
+
+	if len(certs) < 2 {
+		return "", fmt.Errorf("sslChainFile: no intermediate certificate found")
+	}
+
+	for i := 0; i < len(certs)-1; i++ {
+		if err := certs[i].CheckSignatureFrom(certs[i+1]); err != nil {
+			return "", fmt.Errorf(
+				"sslChainFile: certificate '%s' is not signed by '%s', which follows it in the chain: %s",
+				certs[i].Subject,
+				certs[i+1].Subject,
+				err,
+			)
+		}
+	}
+
+	return fullChain.String(), nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package ssl
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "ssl.pem")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadKeyPairFromSources(t *testing.T) {
+	cert, key := generateTestKeyPair(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "app.mycompany.com")
+	t.Setenv("TEST_SSL_CERT", cert)
+	t.Setenv("TEST_SSL_CERT_KEY", key)
+
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"base64", Config{SslCertBase64: encode(cert), SslCertKeyBase64: encode(key)}, false},
+		{"files", Config{SslCertFile: writeTestFile(t, cert), SslCertKeyFile: writeTestFile(t, key)}, false},
+		{"environment variables", Config{SslCertEnv: "TEST_SSL_CERT", SslCertKeyEnv: "TEST_SSL_CERT_KEY"}, false},
+		{"mixed sources", Config{SslCertFile: writeTestFile(t, cert), SslCertKeyEnv: "TEST_SSL_CERT_KEY"}, false},
+		{"base64 and file", Config{SslCertBase64: encode(cert), SslCertFile: writeTestFile(t, cert), SslCertKeyFile: writeTestFile(t, key)}, true},
+		{"missing file", Config{SslCertFile: "/non/existing/ssl.crt", SslCertKeyFile: writeTestFile(t, key)}, true},
+		{"unset environment variable", Config{SslCertEnv: "TEST_SSL_CERT_UNSET", SslCertKeyEnv: "TEST_SSL_CERT_KEY"}, true},
+		{"no key", Config{SslCertFile: writeTestFile(t, cert)}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.Validate("app.mycompany.com")
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestLoadKeyPairWithChainFile(t *testing.T) {
+	intermediateCert, intermediateKey := generateTestCa(t)
+	signed, err := (&Config{
+		SslCertMode:     CertModeSelfSigned,
+		SslCaCertBase64: encode(intermediateCert),
+		SslCaKeyBase64:  encode(intermediateKey),
+	}).LoadKeyPair("app.mycompany.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(signed.Cert))
+	leafCert := string(pem.EncodeToMemory(block))
+
+	config := Config{
+		SslCertFile:    writeTestFile(t, leafCert),
+		SslCertKeyFile: writeTestFile(t, signed.Key),
+		SslChainFile:   writeTestFile(t, intermediateCert),
+	}
+	keyPair, err := config.LoadKeyPair("app.mycompany.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyPair.Cert != leafCert+intermediateCert {
+		t.Errorf("Expected certificate to be followed by intermediate in the chain, got:\n%s", keyPair.Cert)
+	}
+}
+
+func Test_assembleChain(t *testing.T) {
+	intermediateCert, _ := generateTestCa(t)
+	unrelatedCert, _ := generateTestKeyPair(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "app.mycompany.com")
+
+	if _, err := assembleChain(unrelatedCert, intermediateCert); err == nil {
+		t.Error("Expected error for a certificate that is not signed by the next one in the chain")
+	}
+	if _, err := assembleChain(unrelatedCert, ""); err == nil {
+		t.Error("Expected error for a chain without intermediates")
+	}
+}
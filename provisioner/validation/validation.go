@@ -0,0 +1,149 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+// Package validation offers the common checks that all paion-data provisioners run against their decoded Config in
+// Prepare, so that a misconfiguration is reported at "packer validate" time instead of minutes into a cloud build.
+//
+// Each check returns either nil or an error naming the offending HCL field. Provisioners collect them and hand them to
+// Combine, which reports every problem at once in a single packersdk.MultiError:
+//
+//	errs := validation.CheckRequired(&p.config)
+//	errs = append(errs, validation.CheckSourcePath("jarSource", p.config.JarSource))
+//	return validation.Combine(errs...)
+package validation
+
+import (
+	"encoding/base64"
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// hostnameLabel matches a single RFC 1123 label, i.e. the part of a hostname between two dots
+var hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// CheckRequired Returns an error for every field of the provided Config struct that is tagged with `required:"true"`
+// but is left with its zero value. Fields of structs embedded with `mapstructure:",squash"` are checked as well.
+//
+// config: A pointer to, or value of, a provisioner Config struct
+//
+// Returns:
+// A list of errors, one per missing field, each naming the field by its "mapstructure" tag
+func CheckRequired(config interface{}) []error {
+	value := reflect.Indirect(reflect.ValueOf(config))
+	if value.Kind() != reflect.Struct {
+		return []error{fmt.Errorf("cannot validate non-struct config of type %T", config)}
+	}
+
+	return checkRequired(value)
+}
+
+func checkRequired(value reflect.Value) []error {
+	var errs []error
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && strings.Contains(field.Tag.Get("mapstructure"), ",squash") {
+			errs = append(errs, checkRequired(value.Field(i))...)
+			continue
+		}
+		if field.Tag.Get("required") == "true" && value.Field(i).IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required", fieldName(field)))
+		}
+	}
+
+	return errs
+}
+
+// CheckSourcePath Returns an error if a local file or directory referenced by a Config field doesn't exist.
+//
+// An empty path is skipped; pair this with CheckRequired to reject missing values.
+func CheckSourcePath(field string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: source path '%s' is not accessible: %s", field, path, err)
+	}
+
+	return nil
+}
+
+// CheckOfflineSource Returns an error if a local artifact that replaces a download from the internet is missing in
+// offline mode, or if the artifact is given but doesn't exist.
+//
+// Outside offline mode, an empty path is skipped and the artifact is downloaded during the build instead.
+func CheckOfflineSource(offline bool, field string, path string) error {
+	if offline && path == "" {
+		return fmt.Errorf("%s is required in offline mode", field)
+	}
+
+	return CheckSourcePath(field, path)
+}
+
+// CheckDomain Returns an error if a Config field is not a syntactically valid hostname, such as "app.mycompany.com".
+//
+// An empty domain is skipped; pair this with CheckRequired to reject missing values.
+func CheckDomain(field string, domain string) error {
+	if domain == "" {
+		return nil
+	}
+
+	if len(domain) > 253 {
+		return fmt.Errorf("%s: domain '%s' is longer than 253 characters", field, domain)
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		if !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("%s: '%s' is not a valid domain name", field, domain)
+		}
+	}
+
+	return nil
+}
+
+// CheckBase64 Returns an error if a Config field is not a valid standard base64-encoded string.
+//
+// An empty value is skipped; pair this with CheckRequired to reject missing values.
+func CheckBase64(field string, encoded string) error {
+	if encoded == "" {
+		return nil
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(encoded); err != nil {
+		return fmt.Errorf("%s: value is not valid base64: %s", field, err)
+	}
+
+	return nil
+}
+
+// Combine Merges the results of several checks into the error returned by Prepare. Nil results are dropped and nil is
+// returned if no check failed
+func Combine(errs ...error) error {
+	var combined *packersdk.MultiError
+	for _, err := range errs {
+		if err != nil {
+			combined = packersdk.MultiErrorAppend(combined, err)
+		}
+	}
+
+	if combined == nil {
+		return nil
+	}
+
+	return combined
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]; name != "" {
+		return name
+	}
+
+	return field.Name
+}
@@ -0,0 +1,147 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package validation
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+type testSquashedConfig struct {
+	Cert string `mapstructure:"cert" required:"true"`
+}
+
+type testConfig struct {
+	Source  string             `mapstructure:"source" required:"true"`
+	Domain  string             `mapstructure:"domain" required:"true"`
+	HomeDir string             `mapstructure:"homeDir" required:"false"`
+	Ssl     testSquashedConfig `mapstructure:",squash"`
+}
+
+func TestCheckRequired(t *testing.T) {
+	errs := CheckRequired(&testConfig{Source: "/tmp/dist"})
+
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Error() != "domain is required" {
+		t.Errorf("Expected 'domain is required', got '%s'", errs[0])
+	}
+	if errs[1].Error() != "cert is required" {
+		t.Errorf("Expected 'cert is required', got '%s'", errs[1])
+	}
+}
+
+func TestCheckSourcePath(t *testing.T) {
+	existing, err := os.CreateTemp(t.TempDir(), "dist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		name      string
+		path      string
+		expectErr bool
+	}{
+		{"existing file", existing.Name(), false},
+		{"existing directory", t.TempDir(), false},
+		{"missing file", "/non/existing/dist", true},
+		{"empty path", "", false},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			err := CheckSourcePath("distSource", d.path)
+			if (err != nil) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckOfflineSource(t *testing.T) {
+	data := []struct {
+		name      string
+		offline   bool
+		path      string
+		expectErr bool
+	}{
+		{"offline with existing source", true, t.TempDir(), false},
+		{"offline without source", true, "", true},
+		{"offline with missing source", true, "/non/existing/docker-kong", true},
+		{"online without source", false, "", false},
+		{"online with missing source", false, "/non/existing/docker-kong", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			err := CheckOfflineSource(d.offline, "dockerKongSource", d.path)
+			if (err != nil) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckDomain(t *testing.T) {
+	data := []struct {
+		name      string
+		domain    string
+		expectErr bool
+	}{
+		{"regular domain", "app.mycompany.com", false},
+		{"single label", "localhost", false},
+		{"label with hyphen", "my-app.mycompany.com", false},
+		{"scheme included", "https://app.mycompany.com", true},
+		{"leading hyphen", "-app.mycompany.com", true},
+		{"empty label", "app..mycompany.com", true},
+		{"label too long", strings.Repeat("a", 64) + ".com", true},
+		{"empty domain", "", false},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			err := CheckDomain("appDomain", d.domain)
+			if (err != nil) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckBase64(t *testing.T) {
+	data := []struct {
+		name      string
+		encoded   string
+		expectErr bool
+	}{
+		{"valid base64", "YXNkZnNnaHRkeWhyZXJ3ZGZydGV3ZHNmZ3RoeTY0cmV3ZGZyZWd0cmV3d2ZyZw==", false},
+		{"invalid base64", "not base64!", true},
+		{"empty value", "", false},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			err := CheckBase64("sslCertBase64", d.encoded)
+			if (err != nil) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	if err := Combine(nil, nil); err != nil {
+		t.Errorf("Expected no error when all checks pass, got: %v", err)
+	}
+
+	err := Combine(nil, CheckDomain("appDomain", "-"), CheckBase64("sslCertBase64", "!"))
+	if err == nil {
+		t.Fatal("Expected an error when checks fail")
+	}
+	if !strings.Contains(err.Error(), "2 error(s) occurred") {
+		t.Errorf("Expected all failures to be reported, got: %s", err)
+	}
+}
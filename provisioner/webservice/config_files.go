@@ -0,0 +1,177 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"fmt"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConfigDir The directory that config files are installed into, unless they have a destination of their own
+const ConfigDir string = WorkingDir + "/config"
+
+const defaultConfigFileMode string = "0640"
+const stagedConfigFilePrefix string = "webservice-config"
+
+// Spring Boot loads "application.yml" and its profile-specific variants, such as "application-prod.yml", from every
+// directory in spring.config.additional-location
+var springConfigFilePattern = regexp.MustCompile(`^application(-[^.]+)?\.(properties|ya?ml)$`)
+
+// ConfigFile A local file, such as "application.yml", "logback.xml" or a keystore, that is installed next to the JAR
+// file
+type ConfigFile struct {
+	// Source The path to the local file
+	Source string `mapstructure:"source" required:"true"`
+	// Destination The absolute path of the file in remote machine. Default to the file name under ConfigDir
+	Destination string `mapstructure:"destination" required:"false"`
+	// Mode The octal permissions of the file, such as "0600". Default to "0640"
+	Mode string `mapstructure:"mode" required:"false"`
+	// Owner The owner of the file, as "user" or "user:group". Default to the service user
+	Owner string `mapstructure:"owner" required:"false"`
+	// Template Whether the file is a Go template rendered with the variables provided by Packer before upload
+	Template bool `mapstructure:"template" required:"false"`
+}
+
+func (f *ConfigFile) destination() string {
+	if f.Destination == "" {
+		return filepath.Join(ConfigDir, filepath.Base(f.Source))
+	}
+
+	return f.Destination
+}
+
+func (f *ConfigFile) mode() string {
+	if f.Mode == "" {
+		return defaultConfigFileMode
+	}
+
+	return f.Mode
+}
+
+// Returns the owner and group of the file, which default to the service user and its group
+func (f *ConfigFile) owner(serviceUser string) (string, string) {
+	if f.Owner == "" {
+		return serviceUser, serviceUser
+	}
+
+	user, group, found := strings.Cut(f.Owner, ":")
+	if !found {
+		return user, user
+	}
+
+	return user, group
+}
+
+func (c *Config) validateConfigFiles() []error {
+	var errs []error
+
+	for i, f := range c.ConfigFiles {
+		field := fmt.Sprintf("configFiles[%d]", i)
+
+		for _, err := range validation.CheckRequired(&f) {
+			errs = append(errs, fmt.Errorf("%s: %s", field, err))
+		}
+		if err := validation.CheckSourcePath(field+".source", f.Source); err != nil {
+			errs = append(errs, err)
+		}
+
+		if f.Destination != "" && !filepath.IsAbs(f.Destination) {
+			errs = append(errs, fmt.Errorf("%s.destination: '%s' is not an absolute path", field, f.Destination))
+		}
+		if _, err := strconv.ParseUint(f.mode(), 8, 32); err != nil || len(f.mode()) > 4 {
+			errs = append(errs, fmt.Errorf("%s.mode: '%s' is not an octal file mode such as '0640'", field, f.Mode))
+		}
+
+		user, group := f.owner(c.serviceUser())
+		if !serviceUserPattern.MatchString(user) || !serviceUserPattern.MatchString(group) {
+			errs = append(errs, fmt.Errorf("%s.owner: '%s' is not a valid 'user' or 'user:group'", field, f.Owner))
+		}
+	}
+
+	return errs
+}
+
+// Returns the value of spring.config.additional-location, which lists the directories of the Spring config files among
+// the config files, or an empty string if there is none
+func (c *Config) springConfigAdditionalLocation() string {
+	var locations []string
+	seen := make(map[string]bool)
+
+	for _, f := range c.ConfigFiles {
+		destination := f.destination()
+		if !springConfigFilePattern.MatchString(filepath.Base(destination)) {
+			continue
+		}
+
+		location := fmt.Sprintf("file:%s/", filepath.Dir(destination))
+		if !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+
+	return strings.Join(locations, ",")
+}
+
+// Uploads the config files into the home directory, from where getStepInstallingConfigFiles installs them. Template
+// files are rendered first, with the variables Packer provides to the build, such as {{ build `ID` }}
+func uploadConfigFiles(interCtx interpolate.Context, ui packersdk.Ui, communicator packersdk.Communicator, homeDir string, configFiles []ConfigFile) error {
+	for i, f := range configFiles {
+		staged := getStagedConfigFile(homeDir, i, f)
+
+		if !f.Template {
+			if err := file.Provision(interCtx, ui, communicator, f.Source, staged); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := os.ReadFile(f.Source)
+		if err != nil {
+			return fmt.Errorf("error reading config file template '%s': %s", f.Source, err)
+		}
+
+		rendered, err := interpolate.Render(string(content), &interCtx)
+		if err != nil {
+			return fmt.Errorf("error rendering config file template '%s': %s", f.Source, err)
+		}
+
+		if err = ssl.UploadContent(interCtx, ui, communicator, rendered, staged); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the step that installs the staged config files at their destinations with their permissions and owners
+func getStepInstallingConfigFiles(homeDir string, serviceUser string, configFiles []ConfigFile) shell.Step {
+	var commands []string
+	for i, f := range configFiles {
+		user, group := f.owner(serviceUser)
+		staged := getStagedConfigFile(homeDir, i, f)
+		commands = append(
+			commands,
+			fmt.Sprintf("sudo install -D -m %s -o %s -g %s %s %s", f.mode(), user, group, staged, f.destination()),
+			fmt.Sprintf("rm -f %s", staged),
+		)
+	}
+
+	return shell.Step{Name: "Installing webservice config files", Commands: commands}
+}
+
+// Returns where a config file is uploaded to before being installed. The index keeps files of the same name apart
+func getStagedConfigFile(homeDir string, index int, f ConfigFile) string {
+	return filepath.Join(homeDir, fmt.Sprintf("%s-%02d-%s", stagedConfigFilePrefix, index, filepath.Base(f.Source)))
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProvisionConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"my-webservice.jar": "PK",
+		"application.yml":   "instance: {{ build `ID` }}\n",
+		"logback.xml":       "<configuration/>",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"jarSource": filepath.Join(dir, "my-webservice.jar"),
+		"configFiles": []map[string]interface{}{
+			{"source": filepath.Join(dir, "application.yml"), "template": true},
+			{"source": filepath.Join(dir, "logback.xml"), "destination": "/etc/webservice/logback.xml", "mode": "0644", "owner": "root"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, map[string]interface{}{"ID": "i-0123456789"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	application := communicator.Uploaded("/home/ubuntu/webservice-config-00-application.yml")
+	if application == nil || string(application.Content) != "instance: i-0123456789\n" {
+		t.Errorf("Expected rendered application.yml to be uploaded, got: %v", application)
+	}
+	logback := communicator.Uploaded("/home/ubuntu/webservice-config-01-logback.xml")
+	if logback == nil || string(logback.Content) != "<configuration/>" {
+		t.Errorf("Expected logback.xml to be uploaded unchanged, got: %v", logback)
+	}
+
+	scripts := communicator.Scripts()
+	installing := scripts[len(scripts)-1]
+	expectedCommands := []string{
+		"sudo install -D -m 0640 -o webservice -g webservice /home/ubuntu/webservice-config-00-application.yml /opt/webservice/config/application.yml\n",
+		"sudo install -D -m 0644 -o root -g root /home/ubuntu/webservice-config-01-logback.xml /etc/webservice/logback.xml\n",
+	}
+	for _, command := range expectedCommands {
+		if !strings.Contains(installing, command) {
+			t.Errorf("Expected '%s' in script:\n%s", command, installing)
+		}
+	}
+
+	unit := communicator.Uploaded("/home/ubuntu/webservice.service")
+	if unit == nil || !strings.Contains(string(unit.Content), " -Dspring.config.additional-location=file:/opt/webservice/config/ -jar ") {
+		t.Errorf("Expected config directory in spring.config.additional-location, got: %v", unit)
+	}
+}
+
+func TestSpringConfigAdditionalLocation(t *testing.T) {
+	data := []struct {
+		name        string
+		configFiles []ConfigFile
+		expected    string
+	}{
+		{"no config files", nil, ""},
+		{"no Spring config files", []ConfigFile{{Source: "logback.xml"}, {Source: "keystore.p12"}}, ""},
+		{
+			"Spring config files in two directories",
+			[]ConfigFile{
+				{Source: "application.yml"},
+				{Source: "conf/application-prod.properties"},
+				{Source: "application-db.yaml", Destination: "/etc/webservice/application-db.yaml"},
+			},
+			"file:/opt/webservice/config/,file:/etc/webservice/",
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			config := Config{ConfigFiles: d.configFiles}
+			if actual := config.springConfigAdditionalLocation(); actual != d.expected {
+				t.Errorf("Expected '%s', got '%s'", d.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFiles(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "application.yml")
+	if err := os.WriteFile(source, []byte("server.port: 8080"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		name       string
+		configFile ConfigFile
+		expectErr  bool
+	}{
+		{"defaults", ConfigFile{Source: source}, false},
+		{"all fields", ConfigFile{Source: source, Destination: "/etc/webservice/application.yml", Mode: "600", Owner: "root:webservice"}, false},
+		{"missing source", ConfigFile{}, true},
+		{"nonexistent source", ConfigFile{Source: source + ".missing"}, true},
+		{"relative destination", ConfigFile{Source: source, Destination: "config/application.yml"}, true},
+		{"non-octal mode", ConfigFile{Source: source, Mode: "0649"}, true},
+		{"invalid owner", ConfigFile{Source: source, Owner: "root:"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			config := Config{ConfigFiles: []ConfigFile{d.configFile}}
+			errs := config.validateConfigFiles()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
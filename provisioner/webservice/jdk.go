@@ -0,0 +1,240 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"strings"
+)
+
+// DefaultJdkVersion The Java feature release installed, unless another one is configured
+const DefaultJdkVersion int = 17
+
+const (
+	JdkDistributionOpenJdk  string = "openjdk"
+	JdkDistributionTemurin  string = "temurin"
+	JdkDistributionCorretto string = "corretto"
+)
+
+const minJdkVersion int = 11
+
+const adoptiumKeyUrl string = "https://packages.adoptium.net/artifactory/api/gpg/key/public"
+const correttoKeyUrl string = "https://apt.corretto.aws/corretto.key"
+
+// The Adoptium apt repository is organized by the codename of the distribution release, such as "jammy"
+const adoptiumAptSource string = "https://packages.adoptium.net/artifactory/deb $(. /etc/os-release && echo $VERSION_CODENAME) main"
+
+func (c *Config) jdkVersion() int {
+	if c.JdkVersion == 0 {
+		return DefaultJdkVersion
+	}
+
+	return c.JdkVersion
+}
+
+// Returns the configured JDK distribution. Unless one is configured, Amazon Linux gets Amazon Corretto, which is the
+// JDK it packages, and any other distribution gets its own OpenJDK package
+func (c *Config) jdkDistribution(d *distro.Distro) string {
+	if c.JdkDistribution != "" {
+		return c.JdkDistribution
+	}
+	if d.ID == "amzn" {
+		return JdkDistributionCorretto
+	}
+
+	return JdkDistributionOpenJdk
+}
+
+func (c *Config) validateJdk() []error {
+	var errs []error
+
+	if c.jdkVersion() < minJdkVersion {
+		errs = append(errs, fmt.Errorf("jdkVersion: %d is not supported; the minimum is %d", c.JdkVersion, minJdkVersion))
+	}
+
+	switch c.JdkDistribution {
+	case "", JdkDistributionOpenJdk, JdkDistributionTemurin, JdkDistributionCorretto:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"jdkDistribution: '%s' is not one of '%s', '%s' and '%s'",
+			c.JdkDistribution,
+			JdkDistributionOpenJdk,
+			JdkDistributionTemurin,
+			JdkDistributionCorretto,
+		))
+	}
+
+	return errs
+}
+
+// Returns the step that installs the configured JDK, or only its runtime if JRE-only is configured
+func getStepInstallingJdk(d *distro.Distro, config Config) (shell.Step, error) {
+	distribution := config.jdkDistribution(d)
+
+	commands, err := getCommandsInstallingJdk(d, distribution, config.jdkVersion(), config.JreOnly)
+	if err != nil {
+		return shell.Step{}, err
+	}
+
+	return shell.Step{
+		Name:     fmt.Sprintf("Installing %s", getJdkName(distribution, config.jdkVersion(), config.JreOnly)),
+		Commands: commands,
+	}, nil
+}
+
+// Returns a human-readable name of a JDK, such as "Temurin JRE 21"
+func getJdkName(distribution string, version int, jreOnly bool) string {
+	names := map[string]string{
+		JdkDistributionOpenJdk:  "OpenJDK",
+		JdkDistributionTemurin:  "Temurin",
+		JdkDistributionCorretto: "Amazon Corretto",
+	}
+
+	kind := "JDK"
+	if jreOnly {
+		kind = "JRE"
+	}
+
+	return fmt.Sprintf("%s %s %d", names[distribution], kind, version)
+}
+
+// Returns the commands that install a JDK. OpenJDK comes from the package repositories of the distribution itself.
+// Temurin and Corretto come from the repositories of Adoptium and AWS, which are added first, except that Amazon Linux
+// packages Corretto already
+func getCommandsInstallingJdk(d *distro.Distro, distribution string, version int, jreOnly bool) ([]string, error) {
+	pkg, err := getJdkPackage(d, distribution, version, jreOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []string
+	switch {
+	case distribution == JdkDistributionTemurin && d.PackageManager == distro.Apt:
+		commands = getCommandsAddingAptRepository(d, "adoptium", adoptiumKeyUrl, adoptiumAptSource)
+	case distribution == JdkDistributionTemurin:
+		commands = []string{getCommandAddingAdoptiumRpmRepository(d)}
+	case distribution == JdkDistributionCorretto && d.PackageManager == distro.Apt:
+		commands = getCommandsAddingAptRepository(d, "corretto", correttoKeyUrl, "https://apt.corretto.aws stable main")
+	case distribution == JdkDistributionCorretto && d.ID != "amzn":
+		commands = []string{
+			"sudo rpm --import https://yum.corretto.aws/corretto.key",
+			"sudo curl -fsSL -o /etc/yum.repos.d/corretto.repo https://yum.corretto.aws/corretto.repo",
+		}
+	}
+
+	return append(commands, d.InstallCommand(pkg)), nil
+}
+
+// Returns the package of a JDK, or of its headless runtime if jreOnly is true
+func getJdkPackage(d *distro.Distro, distribution string, version int, jreOnly bool) (string, error) {
+	if d.PackageManager == distro.Apk && distribution != JdkDistributionOpenJdk {
+		return "", fmt.Errorf("jdkDistribution: '%s' is not packaged for '%s'; use '%s'", distribution, d.ID, JdkDistributionOpenJdk)
+	}
+
+	switch distribution {
+	case JdkDistributionTemurin:
+		if jreOnly {
+			return fmt.Sprintf("temurin-%d-jre", version), nil
+		}
+		return fmt.Sprintf("temurin-%d-jdk", version), nil
+	case JdkDistributionCorretto:
+		if d.PackageManager == distro.Apt {
+			if jreOnly {
+				return "", fmt.Errorf("jreOnly: Amazon Corretto publishes no JRE-only package for '%s'", d.ID)
+			}
+			return fmt.Sprintf("java-%d-amazon-corretto-jdk", version), nil
+		}
+		if jreOnly {
+			return fmt.Sprintf("java-%d-amazon-corretto-headless", version), nil
+		}
+		return fmt.Sprintf("java-%d-amazon-corretto-devel", version), nil
+	}
+
+	switch {
+	case d.ID == "amzn":
+		return "", fmt.Errorf("jdkDistribution: Amazon Linux packages no OpenJDK; use '%s'", JdkDistributionCorretto)
+	case d.PackageManager == distro.Apt:
+		if jreOnly {
+			return fmt.Sprintf("openjdk-%d-jre-headless", version), nil
+		}
+		return fmt.Sprintf("openjdk-%d-jdk", version), nil
+	case d.PackageManager == distro.Apk:
+		if jreOnly {
+			return fmt.Sprintf("openjdk%d-jre-headless", version), nil
+		}
+		return fmt.Sprintf("openjdk%d-jdk", version), nil
+	default:
+		if jreOnly {
+			return fmt.Sprintf("java-%d-openjdk-headless", version), nil
+		}
+		return fmt.Sprintf("java-%d-openjdk-devel", version), nil
+	}
+}
+
+// Returns the JAVA_HOME of a JDK installed by getJdkPackage. Debian-based distributions name it after the architecture
+func getJavaHome(d *distro.Distro, arch distro.Arch, distribution string, version int, jreOnly bool) string {
+	switch {
+	case distribution == JdkDistributionCorretto:
+		return fmt.Sprintf("/usr/lib/jvm/java-%d-amazon-corretto", version)
+	case distribution == JdkDistributionTemurin && d.PackageManager == distro.Apt:
+		if jreOnly {
+			return fmt.Sprintf("/usr/lib/jvm/temurin-%d-jre-%s", version, arch)
+		}
+		return fmt.Sprintf("/usr/lib/jvm/temurin-%d-jdk-%s", version, arch)
+	case distribution == JdkDistributionTemurin:
+		if jreOnly {
+			return fmt.Sprintf("/usr/lib/jvm/temurin-%d-jre", version)
+		}
+		return fmt.Sprintf("/usr/lib/jvm/temurin-%d-jdk", version)
+	case d.PackageManager == distro.Apt:
+		return fmt.Sprintf("/usr/lib/jvm/java-%d-openjdk-%s", version, arch)
+	case d.PackageManager == distro.Apk:
+		return fmt.Sprintf("/usr/lib/jvm/java-%d-openjdk", version)
+	default:
+		if jreOnly {
+			return fmt.Sprintf("/usr/lib/jvm/jre-%d-openjdk", version)
+		}
+		return fmt.Sprintf("/usr/lib/jvm/java-%d-openjdk", version)
+	}
+}
+
+// Returns the commands that add a signed third-party apt repository
+func getCommandsAddingAptRepository(d *distro.Distro, name string, keyUrl string, source string) []string {
+	keyring := fmt.Sprintf("/etc/apt/keyrings/%s.gpg", name)
+
+	return []string{
+		d.InstallCommand("wget", "gpg"),
+		"sudo mkdir -p /etc/apt/keyrings",
+		fmt.Sprintf("wget -qO - %s | sudo gpg --dearmor --yes -o %s", keyUrl, keyring),
+		fmt.Sprintf(`echo "deb [signed-by=%s] %s" | sudo tee /etc/apt/sources.list.d/%s.list`, keyring, source, name),
+		"sudo apt update",
+	}
+}
+
+// Returns the command that adds the Adoptium RPM repository, which is organized by distribution and major version
+func getCommandAddingAdoptiumRpmRepository(d *distro.Distro) string {
+	name := "rhel"
+	switch {
+	case d.ID == "fedora":
+		name = "fedora"
+	case d.ID == "amzn":
+		name = "amazonlinux"
+	case d.ID == "centos":
+		name = "centos"
+	}
+	majorVersion, _, _ := strings.Cut(d.VersionID, ".")
+
+	repo := strings.Join([]string{
+		"[Adoptium]",
+		"name=Adoptium",
+		fmt.Sprintf("baseurl=https://packages.adoptium.net/artifactory/rpm/%s/%s/$basearch", name, majorVersion),
+		"enabled=1",
+		"gpgcheck=1",
+		fmt.Sprintf("gpgkey=%s", adoptiumKeyUrl),
+	}, `\n`)
+
+	return fmt.Sprintf(`printf '%s\n' | sudo tee /etc/yum.repos.d/adoptium.repo`, repo)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
+	"reflect"
+	"testing"
+)
+
+var ubuntu = &distro.Distro{ID: "ubuntu", VersionID: "22.04", IDLike: []string{"debian"}, PackageManager: distro.Apt}
+var rocky = &distro.Distro{ID: "rocky", VersionID: "9.3", IDLike: []string{"rhel", "centos", "fedora"}, PackageManager: distro.Dnf}
+var amzn = &distro.Distro{ID: "amzn", VersionID: "2023", IDLike: []string{"fedora"}, PackageManager: distro.Dnf}
+
+func TestGetJdk(t *testing.T) {
+	data := []struct {
+		name             string
+		distro           *distro.Distro
+		arch             distro.Arch
+		config           Config
+		expectedPackage  string
+		expectedJavaHome string
+		expectErr        bool
+	}{
+		{"default on Ubuntu", ubuntu, distro.Amd64, Config{}, "openjdk-17-jdk", "/usr/lib/jvm/java-17-openjdk-amd64", false},
+		{"OpenJDK 21 on Graviton", ubuntu, distro.Arm64, Config{JdkVersion: 21}, "openjdk-21-jdk", "/usr/lib/jvm/java-21-openjdk-arm64", false},
+		{"OpenJDK JRE on Ubuntu", ubuntu, distro.Amd64, Config{JreOnly: true}, "openjdk-17-jre-headless", "/usr/lib/jvm/java-17-openjdk-amd64", false},
+		{"OpenJDK JRE on Rocky", rocky, distro.Arm64, Config{JreOnly: true}, "java-17-openjdk-headless", "/usr/lib/jvm/jre-17-openjdk", false},
+		{"Temurin on Ubuntu", ubuntu, distro.Arm64, Config{JdkVersion: 21, JdkDistribution: "temurin"}, "temurin-21-jdk", "/usr/lib/jvm/temurin-21-jdk-arm64", false},
+		{"Temurin JRE on Rocky", rocky, distro.Amd64, Config{JdkDistribution: "temurin", JreOnly: true}, "temurin-17-jre", "/usr/lib/jvm/temurin-17-jre", false},
+		{"default on Amazon Linux", amzn, distro.Arm64, Config{}, "java-17-amazon-corretto-devel", "/usr/lib/jvm/java-17-amazon-corretto", false},
+		{"Corretto JRE on Amazon Linux", amzn, distro.Amd64, Config{JreOnly: true}, "java-17-amazon-corretto-headless", "/usr/lib/jvm/java-17-amazon-corretto", false},
+		{"Corretto on Ubuntu", ubuntu, distro.Amd64, Config{JdkDistribution: "corretto"}, "java-17-amazon-corretto-jdk", "/usr/lib/jvm/java-17-amazon-corretto", false},
+		{"Corretto JRE on Ubuntu", ubuntu, distro.Amd64, Config{JdkDistribution: "corretto", JreOnly: true}, "", "", true},
+		{"OpenJDK on Amazon Linux", amzn, distro.Amd64, Config{JdkDistribution: "openjdk"}, "", "", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			distribution := d.config.jdkDistribution(d.distro)
+
+			pkg, err := getJdkPackage(d.distro, distribution, d.config.jdkVersion(), d.config.JreOnly)
+			if (err != nil) != d.expectErr {
+				t.Fatalf("Expected error: %t, got: %v", d.expectErr, err)
+			}
+			if d.expectErr {
+				return
+			}
+
+			if pkg != d.expectedPackage {
+				t.Errorf("Expected package '%s', got '%s'", d.expectedPackage, pkg)
+			}
+
+			javaHome := getJavaHome(d.distro, d.arch, distribution, d.config.jdkVersion(), d.config.JreOnly)
+			if javaHome != d.expectedJavaHome {
+				t.Errorf("Expected JAVA_HOME '%s', got '%s'", d.expectedJavaHome, javaHome)
+			}
+		})
+	}
+}
+
+func TestGetStepInstallingJdk(t *testing.T) {
+	step, err := getStepInstallingJdk(rocky, Config{JdkVersion: 21, JdkDistribution: "temurin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`printf '[Adoptium]\nname=Adoptium\nbaseurl=https://packages.adoptium.net/artifactory/rpm/rhel/9/$basearch\nenabled=1\ngpgcheck=1\ngpgkey=https://packages.adoptium.net/artifactory/api/gpg/key/public\n' | sudo tee /etc/yum.repos.d/adoptium.repo`,
+		"sudo dnf install -y temurin-21-jdk",
+	}
+
+	if step.Name != "Installing Temurin JDK 21" {
+		t.Errorf("Expected step 'Installing Temurin JDK 21', got '%s'", step.Name)
+	}
+	if !reflect.DeepEqual(expected, step.Commands) {
+		t.Errorf("Expected and actual commands do not match: %v\n\n%v", expected, step.Commands)
+	}
+}
+
+func TestValidateJdk(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"default", Config{}, false},
+		{"Corretto 21", Config{JdkVersion: 21, JdkDistribution: "corretto"}, false},
+		{"Java 8", Config{JdkVersion: 8}, true},
+		{"unknown distribution", Config{JdkDistribution: "zulu"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateJdk()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
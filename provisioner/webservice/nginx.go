@@ -0,0 +1,129 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"text/template"
+)
+
+// DefaultAppPort The port that the webservice listens on behind Nginx, unless another one is configured. It is the
+// default port of Spring Boot
+const DefaultAppPort int = 8080
+
+func (c *Config) appPort() int {
+	if c.AppPort == 0 {
+		return DefaultAppPort
+	}
+
+	return c.AppPort
+}
+
+func (c *Config) validateProxy() []error {
+	if c.AppDomain == "" {
+		if c.AppPort != 0 {
+			return []error{fmt.Errorf("appPort: requires appDomain, since the webservice is proxied only then")}
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.appPort() < 1 || c.appPort() > 65535 {
+		errs = append(errs, fmt.Errorf("appPort: %d is not a valid port", c.AppPort))
+	}
+
+	return append(errs, c.Ssl.Validate(c.AppDomain)...)
+}
+
+// Returns the Nginx config that terminates SSL for the domain and proxies to the webservice on the port.
+//
+// The health endpoint of Spring Boot Actuator is also proxied by the default server on port 80, so that load balancers
+// can probe instances by IP address without a certificate. Other actuator endpoints are reachable only from the machine
+// itself. The HTTP server of the domain answers ACME challenges from the webroot and redirects everything else to HTTPS
+func getNginxConfig(domain string, port int) string {
+	var sslConfigs = struct {
+		Domain        string
+		SslCertDst    string
+		SslCertKeyDst string
+		Port          int
+		Webroot       string
+	}{domain, ssl.SslCertDst, ssl.SslCertKeyDst, port, ssl.AcmeWebroot}
+	var buf bytes.Buffer
+	t := template.Must(template.New("Nginx Config").Parse(`
+server {
+    listen 80 default_server;
+    listen [::]:80 default_server;
+
+    root {{.Webroot}};
+
+    index index.html index.htm index.nginx-debian.html;
+
+    server_name _;
+
+    location = /actuator/health {
+        access_log off;
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_connect_timeout 2s;
+        proxy_read_timeout 5s;
+    }
+
+    location / {
+        try_files $uri $uri/ =404;
+    }
+}
+
+server {
+    server_name {{.Domain}};
+
+    proxy_set_header Host $host;
+    proxy_set_header X-Real-IP $remote_addr;
+    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+    proxy_set_header X-Forwarded-Proto $scheme;
+
+    location / {
+        proxy_pass http://127.0.0.1:{{.Port}};
+    }
+
+    location /actuator/health {
+        access_log off;
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_connect_timeout 2s;
+        proxy_read_timeout 5s;
+    }
+
+    location /actuator/ {
+        allow 127.0.0.1;
+        allow ::1;
+        deny all;
+        proxy_pass http://127.0.0.1:{{.Port}};
+    }
+
+    listen [::]:443 ssl ipv6only=on;
+    listen 443 ssl;
+    ssl_certificate {{.SslCertDst}};
+    ssl_certificate_key {{.SslCertKeyDst}};
+}
+server {
+    listen 80 ;
+    listen [::]:80 ;
+    server_name {{.Domain}};
+
+    location /.well-known/acme-challenge/ {
+        root {{.Webroot}};
+    }
+
+    location / {
+        return 301 https://$host$request_uri;
+    }
+}
+	`))
+
+	if err := t.Execute(&buf, sslConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProvisionWithProxy(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "my-webservice.jar")
+	if err := os.WriteFile(jar, []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	err := provisioner.Prepare(map[string]interface{}{
+		"jarSource":   jar,
+		"appDomain":   "api.mycompany.com",
+		"appPort":     9090,
+		"sslCertMode": ssl.CertModeSelfSigned,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	if err = provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	nginxConfig := communicator.Uploaded("/home/ubuntu/nginx-ssl.conf")
+	if nginxConfig == nil || !strings.Contains(string(nginxConfig.Content), "proxy_pass http://127.0.0.1:9090;") {
+		t.Fatalf("Expected Nginx config proxying to port 9090 to be uploaded, got: %v", nginxConfig)
+	}
+	if communicator.Uploaded("/home/ubuntu/ssl.crt") == nil {
+		t.Error("Expected SSL certificate to be uploaded")
+	}
+
+	scripts := communicator.Scripts()
+	if !strings.Contains(scripts[len(scripts)-1], "sudo mv /home/ubuntu/nginx-ssl.conf /etc/nginx/sites-enabled/default\n") {
+		t.Errorf("Expected Nginx config to be loaded, got:\n%s", scripts[len(scripts)-1])
+	}
+}
+
+func TestGetNginxConfig(t *testing.T) {
+	config := getNginxConfig("api.mycompany.com", 8080)
+
+	expectedSnippets := []string{
+		"server_name api.mycompany.com;",
+		"location = /actuator/health {\n        access_log off;\n        proxy_pass http://127.0.0.1:8080;",
+		"location /actuator/ {\n        allow 127.0.0.1;\n        allow ::1;\n        deny all;",
+		"proxy_set_header X-Forwarded-Proto $scheme;",
+		"location /.well-known/acme-challenge/ {\n        root /var/www/html;",
+		"return 301 https://$host$request_uri;",
+	}
+	for _, snippet := range expectedSnippets {
+		if !strings.Contains(config, snippet) {
+			t.Errorf("Expected '%s' in Nginx config:\n%s", snippet, config)
+		}
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"no proxy", Config{}, false},
+		{"proxy", Config{AppDomain: "api.mycompany.com", Ssl: ssl.Config{SslCertMode: ssl.CertModeSelfSigned}}, false},
+		{"port without domain", Config{AppPort: 9090}, true},
+		{"invalid port", Config{AppDomain: "api.mycompany.com", AppPort: 70000, Ssl: ssl.Config{SslCertMode: ssl.CertModeSelfSigned}}, true},
+		{"missing certificate", Config{AppDomain: "api.mycompany.com"}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateProxy()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}
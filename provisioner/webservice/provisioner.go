@@ -1,7 +1,7 @@
 // Copyright (c) Jiaqi Liu
 // SPDX-License-Identifier: MPL-2.0
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,ConfigFile
 
 package webservice
 
@@ -12,9 +12,12 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/distro"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/file-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/render"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
 	"github.com/paion-data/packer-plugin-paion-data/provisioner/ssl-provisioner"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/validation"
 	"path/filepath"
 )
 
@@ -22,6 +25,23 @@ type Config struct {
 	JarSource string `mapstructure:"jarSource" required:"true"`
 	HomeDir   string `mapstructure:"homeDir" required:"false"`
 
+	JdkVersion      int    `mapstructure:"jdkVersion" required:"false"`
+	JdkDistribution string `mapstructure:"jdkDistribution" required:"false"`
+	JreOnly         bool   `mapstructure:"jreOnly" required:"false"`
+
+	ServiceUser          string            `mapstructure:"serviceUser" required:"false"`
+	JvmOptions           string            `mapstructure:"jvmOptions" required:"false"`
+	SpringProfilesActive string            `mapstructure:"springProfilesActive" required:"false"`
+	Environment          map[string]string `mapstructure:"environment" required:"false"`
+
+	ConfigFiles []ConfigFile `mapstructure:"configFiles" required:"false"`
+
+	AppDomain string `mapstructure:"appDomain" required:"false"`
+	AppPort   int    `mapstructure:"appPort" required:"false"`
+
+	Ssl    ssl.Config    `mapstructure:",squash"`
+	DryRun render.Config `mapstructure:",squash"`
+
 	ctx interpolate.Context
 }
 
@@ -39,38 +59,111 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		return err
 	}
 
-	return nil
+	errs := validation.CheckRequired(&p.config)
+	errs = append(errs, validation.CheckSourcePath("jarSource", p.config.JarSource))
+	errs = append(errs, p.config.validateJdk()...)
+	errs = append(errs, p.config.validateService()...)
+	errs = append(errs, p.config.validateConfigFiles()...)
+	errs = append(errs, validation.CheckDomain("appDomain", p.config.AppDomain))
+	errs = append(errs, p.config.validateProxy()...)
+	errs = append(errs, validation.CheckSourcePath("nginxPackagesSource", p.config.Ssl.NginxPackagesSource))
+	errs = append(errs, p.config.DryRun.Validate()...)
+
+	return validation.Combine(errs...)
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator, generatedData map[string]interface{}) error {
+	p.config.ctx.Data = generatedData
+	return p.config.DryRun.Run(ctx, ui, communicator, p.provision)
+}
+
+func (p *Provisioner) provision(ctx context.Context, ui packersdk.Ui, communicator packersdk.Communicator) error {
 	p.config.HomeDir = ssl.GetHomeDir(p.config.HomeDir)
 
-	jarFileDst := fmt.Sprintf(filepath.Join(p.config.HomeDir, "webservice.jar"))
+	jarFileDst := fmt.Sprintf(filepath.Join(p.config.HomeDir, jarFilename))
 
-	err := file.Provision(p.config.ctx, ui, communicator, p.config.JarSource, jarFileDst)
+	d, err := distro.Detect(ctx, communicator)
 	if err != nil {
 		return err
 	}
+	if !d.Systemd() {
+		return fmt.Errorf("webservice runs as a systemd service, which '%s' does not run", d.ID)
+	}
 
-	return shell.Provision(ctx, ui, communicator, getCommands())
-}
+	arch, err := distro.DetectArch(ctx, communicator)
+	if err != nil {
+		return err
+	}
+
+	steps, err := getSteps(d, p.config)
+	if err != nil {
+		return err
+	}
 
-func getCommands() []string {
-	return append(getCommandsUpdatingUbuntu(), getCommandsInstallingJDK17()...)
+	err = file.Provision(p.config.ctx, ui, communicator, p.config.JarSource, jarFileDst)
+	if err != nil {
+		return err
+	}
+
+	serviceDst := filepath.Join(p.config.HomeDir, ServiceName+".service")
+	javaHome := getJavaHome(d, arch, p.config.jdkDistribution(d), p.config.jdkVersion(), p.config.JreOnly)
+	err = ssl.UploadContent(p.config.ctx, ui, communicator, getServiceUnit(p.config, javaHome), serviceDst)
+	if err != nil {
+		return err
+	}
+
+	err = uploadConfigFiles(p.config.ctx, ui, communicator, p.config.HomeDir, p.config.ConfigFiles)
+	if err != nil {
+		return err
+	}
+
+	err = shell.Provision(ctx, ui, communicator, steps)
+	if err != nil {
+		return err
+	}
+	if p.config.AppDomain == "" {
+		return nil
+	}
+
+	return ssl.Provision(
+		ctx,
+		p.config.ctx,
+		ui,
+		communicator,
+		d,
+		p.config.HomeDir,
+		p.config.Ssl,
+		p.config.AppDomain,
+		getNginxConfig(p.config.AppDomain, p.config.appPort()),
+	)
 }
 
-func getCommandsUpdatingUbuntu() []string {
-	return []string{
-		"sudo apt update && sudo apt upgrade -y",
-		"sudo apt install software-properties-common -y",
+func getSteps(d *distro.Distro, config Config) ([]shell.Step, error) {
+	stepInstallingJdk, err := getStepInstallingJdk(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []shell.Step{
+		{Name: "Updating system packages", Commands: getCommandsUpdatingSystem(d)},
+		stepInstallingJdk,
+		getStepCreatingServiceUser(config.serviceUser()),
+		getStepInstallingService(config.HomeDir, config.serviceUser()),
+	}
+	if len(config.ConfigFiles) > 0 {
+		steps = append(steps, getStepInstallingConfigFiles(config.HomeDir, config.serviceUser(), config.ConfigFiles))
 	}
+
+	return steps, nil
 }
 
-// Install JDK 17 - https://www.rosehosting.com/blog/how-to-install-java-17-lts-on-ubuntu-20-04/
-func getCommandsInstallingJDK17() []string {
+func getCommandsUpdatingSystem(d *distro.Distro) []string {
+	if d.PackageManager != distro.Apt {
+		return []string{d.UpgradeCommand()}
+	}
+
 	return []string{
-		"sudo apt update -y",
-		"sudo apt install openjdk-17-jdk -y",
-		"export JAVA_HOME=/usr/lib/jvm/java-17-openjdk-amd64",
+		d.UpgradeCommand(),
+		d.InstallCommand("software-properties-common"),
 	}
 }
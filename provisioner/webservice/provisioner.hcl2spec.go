@@ -10,8 +10,37 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	JarSource *string `mapstructure:"jarSource" required:"true" cty:"jarSource" hcl:"jarSource"`
-	HomeDir   *string `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	JarSource                     *string           `mapstructure:"jarSource" required:"true" cty:"jarSource" hcl:"jarSource"`
+	HomeDir                       *string           `mapstructure:"homeDir" required:"false" cty:"homeDir" hcl:"homeDir"`
+	JdkVersion                    *int              `mapstructure:"jdkVersion" required:"false" cty:"jdkVersion" hcl:"jdkVersion"`
+	JdkDistribution               *string           `mapstructure:"jdkDistribution" required:"false" cty:"jdkDistribution" hcl:"jdkDistribution"`
+	JreOnly                       *bool             `mapstructure:"jreOnly" required:"false" cty:"jreOnly" hcl:"jreOnly"`
+	ServiceUser                   *string           `mapstructure:"serviceUser" required:"false" cty:"serviceUser" hcl:"serviceUser"`
+	JvmOptions                    *string           `mapstructure:"jvmOptions" required:"false" cty:"jvmOptions" hcl:"jvmOptions"`
+	SpringProfilesActive          *string           `mapstructure:"springProfilesActive" required:"false" cty:"springProfilesActive" hcl:"springProfilesActive"`
+	Environment                   map[string]string `mapstructure:"environment" required:"false" cty:"environment" hcl:"environment"`
+	ConfigFiles                   []FlatConfigFile  `mapstructure:"configFiles" required:"false" cty:"configFiles" hcl:"configFiles"`
+	AppDomain                     *string           `mapstructure:"appDomain" required:"false" cty:"appDomain" hcl:"appDomain"`
+	AppPort                       *int              `mapstructure:"appPort" required:"false" cty:"appPort" hcl:"appPort"`
+	SslCertMode                   *string           `mapstructure:"sslCertMode" required:"false" cty:"sslCertMode" hcl:"sslCertMode"`
+	SslCertBase64                 *string           `mapstructure:"sslCertBase64" required:"false" cty:"sslCertBase64" hcl:"sslCertBase64"`
+	SslCertKeyBase64              *string           `mapstructure:"sslCertKeyBase64" required:"false" cty:"sslCertKeyBase64" hcl:"sslCertKeyBase64"`
+	SslCertFile                   *string           `mapstructure:"sslCertFile" required:"false" cty:"sslCertFile" hcl:"sslCertFile"`
+	SslCertKeyFile                *string           `mapstructure:"sslCertKeyFile" required:"false" cty:"sslCertKeyFile" hcl:"sslCertKeyFile"`
+	SslCertEnv                    *string           `mapstructure:"sslCertEnv" required:"false" cty:"sslCertEnv" hcl:"sslCertEnv"`
+	SslCertKeyEnv                 *string           `mapstructure:"sslCertKeyEnv" required:"false" cty:"sslCertKeyEnv" hcl:"sslCertKeyEnv"`
+	SslChainFile                  *string           `mapstructure:"sslChainFile" required:"false" cty:"sslChainFile" hcl:"sslChainFile"`
+	SslCertExpiryWindowDays       *int              `mapstructure:"sslCertExpiryWindowDays" required:"false" cty:"sslCertExpiryWindowDays" hcl:"sslCertExpiryWindowDays"`
+	SslCertFailWithinExpiryWindow *bool             `mapstructure:"sslCertFailWithinExpiryWindow" required:"false" cty:"sslCertFailWithinExpiryWindow" hcl:"sslCertFailWithinExpiryWindow"`
+	SslCaCertBase64               *string           `mapstructure:"sslCaCertBase64" required:"false" cty:"sslCaCertBase64" hcl:"sslCaCertBase64"`
+	SslCaKeyBase64                *string           `mapstructure:"sslCaKeyBase64" required:"false" cty:"sslCaKeyBase64" hcl:"sslCaKeyBase64"`
+	SslSelfSignedValidityDays     *int              `mapstructure:"sslSelfSignedValidityDays" required:"false" cty:"sslSelfSignedValidityDays" hcl:"sslSelfSignedValidityDays"`
+	SslAcmeEmail                  *string           `mapstructure:"sslAcmeEmail" required:"false" cty:"sslAcmeEmail" hcl:"sslAcmeEmail"`
+	SslAcmeDirectoryUrl           *string           `mapstructure:"sslAcmeDirectoryUrl" required:"false" cty:"sslAcmeDirectoryUrl" hcl:"sslAcmeDirectoryUrl"`
+	SslAcmeDirectoryCaBase64      *string           `mapstructure:"sslAcmeDirectoryCaBase64" required:"false" cty:"sslAcmeDirectoryCaBase64" hcl:"sslAcmeDirectoryCaBase64"`
+	NginxPackagesSource           *string           `mapstructure:"nginxPackagesSource" required:"false" cty:"nginxPackagesSource" hcl:"nginxPackagesSource"`
+	DryRunDir                     *string           `mapstructure:"dryRunDir" required:"false" cty:"dryRunDir" hcl:"dryRunDir"`
+	DryRunDistro                  *string           `mapstructure:"dryRunDistro" required:"false" cty:"dryRunDistro" hcl:"dryRunDistro"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -26,8 +55,68 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"jarSource": &hcldec.AttrSpec{Name: "jarSource", Type: cty.String, Required: false},
-		"homeDir":   &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"jarSource":                     &hcldec.AttrSpec{Name: "jarSource", Type: cty.String, Required: false},
+		"homeDir":                       &hcldec.AttrSpec{Name: "homeDir", Type: cty.String, Required: false},
+		"jdkVersion":                    &hcldec.AttrSpec{Name: "jdkVersion", Type: cty.Number, Required: false},
+		"jdkDistribution":               &hcldec.AttrSpec{Name: "jdkDistribution", Type: cty.String, Required: false},
+		"jreOnly":                       &hcldec.AttrSpec{Name: "jreOnly", Type: cty.Bool, Required: false},
+		"serviceUser":                   &hcldec.AttrSpec{Name: "serviceUser", Type: cty.String, Required: false},
+		"jvmOptions":                    &hcldec.AttrSpec{Name: "jvmOptions", Type: cty.String, Required: false},
+		"springProfilesActive":          &hcldec.AttrSpec{Name: "springProfilesActive", Type: cty.String, Required: false},
+		"environment":                   &hcldec.AttrSpec{Name: "environment", Type: cty.Map(cty.String), Required: false},
+		"configFiles":                   &hcldec.BlockListSpec{TypeName: "configFiles", Nested: hcldec.ObjectSpec((*FlatConfigFile)(nil).HCL2Spec())},
+		"appDomain":                     &hcldec.AttrSpec{Name: "appDomain", Type: cty.String, Required: false},
+		"appPort":                       &hcldec.AttrSpec{Name: "appPort", Type: cty.Number, Required: false},
+		"sslCertMode":                   &hcldec.AttrSpec{Name: "sslCertMode", Type: cty.String, Required: false},
+		"sslCertBase64":                 &hcldec.AttrSpec{Name: "sslCertBase64", Type: cty.String, Required: false},
+		"sslCertKeyBase64":              &hcldec.AttrSpec{Name: "sslCertKeyBase64", Type: cty.String, Required: false},
+		"sslCertFile":                   &hcldec.AttrSpec{Name: "sslCertFile", Type: cty.String, Required: false},
+		"sslCertKeyFile":                &hcldec.AttrSpec{Name: "sslCertKeyFile", Type: cty.String, Required: false},
+		"sslCertEnv":                    &hcldec.AttrSpec{Name: "sslCertEnv", Type: cty.String, Required: false},
+		"sslCertKeyEnv":                 &hcldec.AttrSpec{Name: "sslCertKeyEnv", Type: cty.String, Required: false},
+		"sslChainFile":                  &hcldec.AttrSpec{Name: "sslChainFile", Type: cty.String, Required: false},
+		"sslCertExpiryWindowDays":       &hcldec.AttrSpec{Name: "sslCertExpiryWindowDays", Type: cty.Number, Required: false},
+		"sslCertFailWithinExpiryWindow": &hcldec.AttrSpec{Name: "sslCertFailWithinExpiryWindow", Type: cty.Bool, Required: false},
+		"sslCaCertBase64":               &hcldec.AttrSpec{Name: "sslCaCertBase64", Type: cty.String, Required: false},
+		"sslCaKeyBase64":                &hcldec.AttrSpec{Name: "sslCaKeyBase64", Type: cty.String, Required: false},
+		"sslSelfSignedValidityDays":     &hcldec.AttrSpec{Name: "sslSelfSignedValidityDays", Type: cty.Number, Required: false},
+		"sslAcmeEmail":                  &hcldec.AttrSpec{Name: "sslAcmeEmail", Type: cty.String, Required: false},
+		"sslAcmeDirectoryUrl":           &hcldec.AttrSpec{Name: "sslAcmeDirectoryUrl", Type: cty.String, Required: false},
+		"sslAcmeDirectoryCaBase64":      &hcldec.AttrSpec{Name: "sslAcmeDirectoryCaBase64", Type: cty.String, Required: false},
+		"nginxPackagesSource":           &hcldec.AttrSpec{Name: "nginxPackagesSource", Type: cty.String, Required: false},
+		"dryRunDir":                     &hcldec.AttrSpec{Name: "dryRunDir", Type: cty.String, Required: false},
+		"dryRunDistro":                  &hcldec.AttrSpec{Name: "dryRunDistro", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatConfigFile is an auto-generated flat version of ConfigFile.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfigFile struct {
+	Source      *string `mapstructure:"source" required:"true" cty:"source" hcl:"source"`
+	Destination *string `mapstructure:"destination" required:"false" cty:"destination" hcl:"destination"`
+	Mode        *string `mapstructure:"mode" required:"false" cty:"mode" hcl:"mode"`
+	Owner       *string `mapstructure:"owner" required:"false" cty:"owner" hcl:"owner"`
+	Template    *bool   `mapstructure:"template" required:"false" cty:"template" hcl:"template"`
+}
+
+// FlatMapstructure returns a new FlatConfigFile.
+// FlatConfigFile is an auto-generated flat version of ConfigFile.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ConfigFile) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfigFile)
+}
+
+// HCL2Spec returns the hcl spec of a ConfigFile.
+// This spec is used by HCL to read the fields of ConfigFile.
+// The decoded values from this spec will then be applied to a FlatConfigFile.
+func (*FlatConfigFile) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"source":      &hcldec.AttrSpec{Name: "source", Type: cty.String, Required: false},
+		"destination": &hcldec.AttrSpec{Name: "destination", Type: cty.String, Required: false},
+		"mode":        &hcldec.AttrSpec{Name: "mode", Type: cty.String, Required: false},
+		"owner":       &hcldec.AttrSpec{Name: "owner", Type: cty.String, Required: false},
+		"template":    &hcldec.AttrSpec{Name: "template", Type: cty.Bool, Required: false},
 	}
 	return s
 }
@@ -0,0 +1,94 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"context"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/communicatortest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "my-webservice.jar")
+	if err := os.WriteFile(jar, []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	if err := provisioner.Prepare(map[string]interface{}{"jarSource": jar}); err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New().On("id -u webservice", 1, "")
+	communicator.OsRelease = "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n"
+	if err := provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadedJar := communicator.Uploaded("/home/ubuntu/webservice.jar")
+	if uploadedJar == nil || string(uploadedJar.Content) != "PK" {
+		t.Errorf("Expected JAR to be uploaded, got: %v", uploadedJar)
+	}
+
+	scripts := communicator.Scripts()
+	if len(scripts) != 4 || !strings.Contains(scripts[1], "sudo dnf install -y java-17-openjdk-devel\n") {
+		t.Fatalf("Expected JDK 17 to be installed with dnf, got: %s", scripts)
+	}
+	if !strings.Contains(scripts[2], "sudo useradd --system --no-create-home --shell /usr/sbin/nologin webservice\n") {
+		t.Errorf("Expected service user to be created, got:\n%s", scripts[2])
+	}
+	if !strings.Contains(scripts[3], "sudo systemctl enable webservice\n") {
+		t.Errorf("Expected service to be enabled, got:\n%s", scripts[3])
+	}
+
+	unit := communicator.Uploaded("/home/ubuntu/webservice.service")
+	if unit == nil || !strings.Contains(string(unit.Content), "ExecStart=/usr/lib/jvm/java-17-openjdk/bin/java -jar /opt/webservice/webservice.jar\n") {
+		t.Errorf("Expected systemd unit to be uploaded, got: %v", unit)
+	}
+}
+
+func TestProvisionWithoutSystemd(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "my-webservice.jar")
+	if err := os.WriteFile(jar, []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	if err := provisioner.Prepare(map[string]interface{}{"jarSource": jar}); err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	communicator.OsRelease = "ID=alpine\nVERSION_ID=3.19.1\n"
+	if err := provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err == nil {
+		t.Error("Expected error provisioning a distribution without systemd")
+	}
+}
+
+func TestProvisionOnGraviton(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "my-webservice.jar")
+	if err := os.WriteFile(jar, []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provisioner := new(Provisioner)
+	if err := provisioner.Prepare(map[string]interface{}{"jarSource": jar, "jdkVersion": 21, "jreOnly": true}); err != nil {
+		t.Fatal(err)
+	}
+
+	communicator := communicatortest.New()
+	communicator.Machine = "aarch64"
+	if err := provisioner.Provision(context.Background(), packersdk.TestUi(t), communicator, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	unit := communicator.Uploaded("/home/ubuntu/webservice.service")
+	if unit == nil || !strings.Contains(string(unit.Content), "Environment=\"JAVA_HOME=/usr/lib/jvm/java-21-openjdk-arm64\"\n") {
+		t.Errorf("Expected JAVA_HOME of arm64 JRE 21 in systemd unit, got: %v", unit)
+	}
+}
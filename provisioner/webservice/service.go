@@ -0,0 +1,160 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/paion-data/packer-plugin-paion-data/provisioner/shell"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultServiceUser The system user that runs the webservice, unless another one is configured
+const DefaultServiceUser string = "webservice"
+
+// ServiceName The name of the systemd service that runs the webservice
+const ServiceName string = "webservice"
+
+// WorkingDir The working directory of the webservice, which holds its JAR file
+const WorkingDir string = "/opt/webservice"
+
+const jarFilename string = "webservice.jar"
+const systemdUnitDir string = "/etc/systemd/system"
+
+var serviceUserPattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (c *Config) serviceUser() string {
+	if c.ServiceUser == "" {
+		return DefaultServiceUser
+	}
+
+	return c.ServiceUser
+}
+
+func (c *Config) validateService() []error {
+	var errs []error
+
+	if !serviceUserPattern.MatchString(c.serviceUser()) {
+		errs = append(errs, fmt.Errorf("serviceUser: '%s' is not a valid Linux user name", c.ServiceUser))
+	}
+
+	for _, name := range shell.SortedKeys(c.Environment) {
+		if !envNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("environment: '%s' is not a valid environment variable name", name))
+		}
+	}
+	if _, ok := c.Environment["SERVER_PORT"]; ok && c.AppDomain != "" {
+		errs = append(errs, fmt.Errorf("environment: SERVER_PORT is set from appPort, which Nginx proxies to"))
+	}
+
+	return errs
+}
+
+// Returns the systemd unit that runs the JAR file as the service user and restarts it whenever it fails. Behind Nginx,
+// the webservice listens on appPort through SERVER_PORT, which Spring Boot reads as server.port
+func getServiceUnit(config Config, javaHome string) string {
+	environment := map[string]string{"JAVA_HOME": javaHome}
+	if config.SpringProfilesActive != "" {
+		environment["SPRING_PROFILES_ACTIVE"] = config.SpringProfilesActive
+	}
+	if config.AppDomain != "" {
+		environment["SERVER_PORT"] = strconv.Itoa(config.appPort())
+	}
+	for name, value := range config.Environment {
+		environment[name] = value
+	}
+
+	var assignments []string
+	for _, name := range shell.SortedKeys(environment) {
+		assignments = append(assignments, quoteUnitValue(fmt.Sprintf("%s=%s", name, environment[name])))
+	}
+
+	var serviceConfigs = struct {
+		User        string
+		WorkingDir  string
+		Environment []string
+		ExecStart   string
+	}{
+		config.serviceUser(),
+		WorkingDir,
+		assignments,
+		getExecStart(config, javaHome),
+	}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("Webservice Service").Parse(`[Unit]
+Description=Spring Boot webservice
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User={{.User}}
+Group={{.User}}
+WorkingDirectory={{.WorkingDir}}
+{{- range .Environment}}
+Environment={{.}}
+{{- end}}
+ExecStart={{.ExecStart}}
+SuccessExitStatus=143
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`))
+	if err := t.Execute(&buf, serviceConfigs); err != nil {
+		panic(err)
+	}
+
+	return buf.String()
+}
+
+// Returns the command that runs the JAR file. Spring config files among the config files are passed to Spring Boot with
+// spring.config.additional-location, so that they override the config packaged in the JAR file
+func getExecStart(config Config, javaHome string) string {
+	options := strings.Fields(config.JvmOptions)
+	if location := config.springConfigAdditionalLocation(); location != "" {
+		options = append(options, fmt.Sprintf("-Dspring.config.additional-location=%s", location))
+	}
+
+	return strings.Join(append(append([]string{javaHome + "/bin/java"}, options...), "-jar", filepath.Join(WorkingDir, jarFilename)), " ")
+}
+
+// Returns the step that creates the system user running the webservice, unless it exists already
+func getStepCreatingServiceUser(user string) shell.Step {
+	return shell.Step{
+		Name:     fmt.Sprintf("Creating service user %s", user),
+		Commands: []string{fmt.Sprintf("sudo useradd --system --no-create-home --shell /usr/sbin/nologin %s", user)},
+		Unless:   fmt.Sprintf("id -u %s", user),
+	}
+}
+
+// Returns the step that moves the uploaded JAR file and systemd unit into place and enables the service at boot. The
+// service is not started during the build. systemd is reloaded only if it is running, so that images can be built in
+// containers as well
+func getStepInstallingService(homeDir string, user string) shell.Step {
+	return shell.Step{
+		Name: "Installing webservice systemd service",
+		Commands: []string{
+			fmt.Sprintf("sudo mkdir -p %s", WorkingDir),
+			fmt.Sprintf("sudo mv %s %s", filepath.Join(homeDir, jarFilename), filepath.Join(WorkingDir, jarFilename)),
+			fmt.Sprintf("sudo chown -R %s:%s %s", user, user, WorkingDir),
+			fmt.Sprintf("sudo mv %s %s/", filepath.Join(homeDir, ServiceName+".service"), systemdUnitDir),
+			"if [ -d /run/systemd/system ]; then sudo systemctl daemon-reload; fi",
+			fmt.Sprintf("sudo systemctl enable %s", ServiceName),
+		},
+	}
+}
+
+// Double-quotes a value of a systemd unit setting, escaping the characters that systemd would interpret
+func quoteUnitValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(value)
+	return `"` + value + `"`
+}
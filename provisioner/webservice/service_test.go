@@ -0,0 +1,92 @@
+// Copyright (c) Jiaqi Liu
+// SPDX-License-Identifier: MPL-2.0
+
+package webservice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetServiceUnit(t *testing.T) {
+	config := Config{
+		ServiceUser:          "spring",
+		JvmOptions:           "-Xms256m  -Xmx512m",
+		SpringProfilesActive: "prod",
+		Environment:          map[string]string{"DB_URL": "jdbc:mysql://db:3306/app?ssl=true", "GREETING": `say "100%"`},
+	}
+
+	expected := `[Unit]
+Description=Spring Boot webservice
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User=spring
+Group=spring
+WorkingDirectory=/opt/webservice
+Environment="DB_URL=jdbc:mysql://db:3306/app?ssl=true"
+Environment="GREETING=say \"100%%\""
+Environment="JAVA_HOME=/usr/lib/jvm/java-17-openjdk-amd64"
+Environment="SPRING_PROFILES_ACTIVE=prod"
+ExecStart=/usr/lib/jvm/java-17-openjdk-amd64/bin/java -Xms256m -Xmx512m -jar /opt/webservice/webservice.jar
+SuccessExitStatus=143
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	if actual := getServiceUnit(config, "/usr/lib/jvm/java-17-openjdk-amd64"); actual != expected {
+		t.Errorf("Expected unit:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestGetServiceUnitBehindNginx(t *testing.T) {
+	data := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"default port", Config{AppDomain: "api.mycompany.com"}, "Environment=\"SERVER_PORT=8080\"\n"},
+		{"custom port", Config{AppDomain: "api.mycompany.com", AppPort: 9090}, "Environment=\"SERVER_PORT=9090\"\n"},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			if unit := getServiceUnit(d.config, "/usr/lib/jvm/java-17-openjdk-amd64"); !strings.Contains(unit, d.expected) {
+				t.Errorf("Expected '%s' in unit:\n%s", d.expected, unit)
+			}
+		})
+	}
+
+	if unit := getServiceUnit(Config{}, "/usr/lib/jvm/java-17-openjdk-amd64"); strings.Contains(unit, "SERVER_PORT") {
+		t.Errorf("Expected no SERVER_PORT without Nginx, got:\n%s", unit)
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	data := []struct {
+		name      string
+		config    Config
+		expectErr bool
+	}{
+		{"default user", Config{}, false},
+		{"custom user and environment", Config{ServiceUser: "spring-app", Environment: map[string]string{"DB_URL": "x"}}, false},
+		{"invalid user", Config{ServiceUser: "Root User"}, true},
+		{"invalid environment variable name", Config{Environment: map[string]string{"DB-URL": "x"}}, true},
+		{"server port without Nginx", Config{Environment: map[string]string{"SERVER_PORT": "9090"}}, false},
+		{"server port behind Nginx", Config{AppDomain: "api.mycompany.com", Environment: map[string]string{"SERVER_PORT": "9090"}}, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			errs := d.config.validateService()
+			if (len(errs) > 0) != d.expectErr {
+				t.Errorf("Expected error: %t, got: %v", d.expectErr, errs)
+			}
+		})
+	}
+}